@@ -0,0 +1,195 @@
+package fsm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// AuditRecord records one Fire call for an audit trail.
+type AuditRecord[S, T ~uint] struct {
+	Time    time.Time  // when Fire was called, on the spec's Clock
+	Actor   string     // who fired the trigger; set by AuditRecorder.WithActor
+	Trigger T          // the trigger fired
+	From    S          // the state before Fire
+	To      S          // the state after Fire; equal to From unless it transitioned
+	Result  FireResult // the outcome of Fire
+	Error   string     // the error Fire returned, if any
+	Payload any        // the redacted payload; nil unless AuditRecorder.WithPayload is used
+}
+
+// MarshalJSON encodes the record with states, trigger and result as their String() names, for human-readable logs.
+func (r AuditRecord[S, T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Time    time.Time `json:"time"`
+		Actor   string    `json:"actor,omitempty"`
+		Trigger string    `json:"trigger"`
+		From    string    `json:"from"`
+		To      string    `json:"to"`
+		Result  string    `json:"result"`
+		Error   string    `json:"error,omitempty"`
+		Payload any       `json:"payload,omitempty"`
+	}{
+		Time:    r.Time,
+		Actor:   r.Actor,
+		Trigger: fmt.Sprint(r.Trigger),
+		From:    fmt.Sprint(r.From),
+		To:      fmt.Sprint(r.To),
+		Result:  r.Result.String(),
+		Error:   r.Error,
+		Payload: r.Payload,
+	})
+}
+
+// AuditSink stores audit records.
+type AuditSink[S, T ~uint] interface {
+	WriteRecord(ctx context.Context, record AuditRecord[S, T]) error
+}
+
+// AuditRecorder turns every Fire call — transitions as well as rejections and failures — into an AuditRecord written to
+// its sinks. Attach it to a Builder or a Machine with Observe(recorder.Observer()).
+type AuditRecorder[S, T ~uint, Payload any] struct {
+	sinks   []AuditSink[S, T]
+	actor   func(ctx context.Context, payload Payload) string
+	redact  func(payload Payload) any
+	onError func(ctx context.Context, record AuditRecord[S, T], err error)
+}
+
+// NewAuditRecorder creates an AuditRecorder writing to sinks, in order.
+func NewAuditRecorder[S, T ~uint, Payload any](sinks ...AuditSink[S, T]) *AuditRecorder[S, T, Payload] {
+	return &AuditRecorder[S, T, Payload]{sinks: sinks}
+}
+
+// WithActor sets the function identifying who fired a trigger, e.g. from an authenticated user in ctx.
+func (r *AuditRecorder[S, T, Payload]) WithActor(actor func(ctx context.Context, payload Payload) string) *AuditRecorder[S, T, Payload] {
+	r.actor = actor
+	return r
+}
+
+// WithPayload records the value redact returns for each payload. Return only what may be retained, e.g. a copy with
+// personal data masked. Without it, payloads are not recorded.
+func (r *AuditRecorder[S, T, Payload]) WithPayload(redact func(payload Payload) any) *AuditRecorder[S, T, Payload] {
+	r.redact = redact
+	return r
+}
+
+// OnError sets the function called when a sink fails to write a record. Without it, sink errors are dropped.
+func (r *AuditRecorder[S, T, Payload]) OnError(
+	onError func(ctx context.Context, record AuditRecord[S, T], err error),
+) *AuditRecorder[S, T, Payload] {
+	r.onError = onError
+	return r
+}
+
+// Observer returns the observer that feeds the recorder.
+func (r *AuditRecorder[S, T, Payload]) Observer() Observer[S, T, Payload] {
+	return Observer[S, T, Payload]{OnFire: r.record}
+}
+
+func (r *AuditRecorder[S, T, Payload]) record(ctx context.Context, event FireEvent[S, T, Payload]) {
+	record := AuditRecord[S, T]{
+		Time:    event.At,
+		Trigger: event.Trigger,
+		From:    event.From,
+		To:      event.To,
+		Result:  event.Result(),
+	}
+	if event.Err != nil {
+		record.Error = event.Err.Error()
+	}
+	if r.actor != nil {
+		record.Actor = r.actor(ctx, event.Payload)
+	}
+	if r.redact != nil {
+		record.Payload = r.redact(event.Payload)
+	}
+	for _, sink := range r.sinks {
+		if err := sink.WriteRecord(ctx, record); err != nil && r.onError != nil {
+			r.onError(ctx, record, err)
+		}
+	}
+}
+
+// SlogAuditSink writes audit records to a slog.Logger at Info level.
+type SlogAuditSink[S, T ~uint] struct {
+	logger *slog.Logger
+}
+
+// NewSlogAuditSink creates a SlogAuditSink writing to logger.
+func NewSlogAuditSink[S, T ~uint](logger *slog.Logger) *SlogAuditSink[S, T] {
+	return &SlogAuditSink[S, T]{logger: logger}
+}
+
+// WriteRecord logs the record with one attribute per field.
+func (s *SlogAuditSink[S, T]) WriteRecord(ctx context.Context, record AuditRecord[S, T]) error {
+	attrs := []slog.Attr{
+		slog.Time("at", record.Time),
+		slog.String("trigger", fmt.Sprint(record.Trigger)),
+		slog.String("from", fmt.Sprint(record.From)),
+		slog.String("to", fmt.Sprint(record.To)),
+		slog.String("result", record.Result.String()),
+	}
+	if record.Actor != "" {
+		attrs = append(attrs, slog.String("actor", record.Actor))
+	}
+	if record.Error != "" {
+		attrs = append(attrs, slog.String("error", record.Error))
+	}
+	if record.Payload != nil {
+		attrs = append(attrs, slog.Any("payload", record.Payload))
+	}
+	s.logger.LogAttrs(ctx, slog.LevelInfo, "fsm audit", attrs...)
+	return nil
+}
+
+// JSONLinesAuditSink writes audit records as JSON lines, e.g. to an append-only file. It is safe for concurrent use.
+type JSONLinesAuditSink[S, T ~uint] struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLinesAuditSink creates a JSONLinesAuditSink writing to w. To write to a file, open it with
+// os.O_APPEND|os.O_CREATE|os.O_WRONLY.
+func NewJSONLinesAuditSink[S, T ~uint](w io.Writer) *JSONLinesAuditSink[S, T] {
+	return &JSONLinesAuditSink[S, T]{w: w}
+}
+
+// WriteRecord writes the record as one line of JSON.
+func (s *JSONLinesAuditSink[S, T]) WriteRecord(_ context.Context, record AuditRecord[S, T]) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("encoding audit record: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("writing audit record: %w", err)
+	}
+	return nil
+}
+
+// MemoryAuditSink keeps audit records in memory, for tests. It is safe for concurrent use.
+type MemoryAuditSink[S, T ~uint] struct {
+	mu      sync.Mutex
+	records []AuditRecord[S, T]
+}
+
+// WriteRecord appends the record.
+func (s *MemoryAuditSink[S, T]) WriteRecord(_ context.Context, record AuditRecord[S, T]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+// Records returns the records written so far, in order.
+func (s *MemoryAuditSink[S, T]) Records() []AuditRecord[S, T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.records)
+}
@@ -0,0 +1,115 @@
+package fsm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type actorKey struct{}
+
+func newAuditedSpec(recorder *AuditRecorder[state, trigger, payload]) *Spec[state, trigger, payload] {
+	builder := NewBuilder[state, trigger, payload]().
+		WithClock(&stepClock{now: time.Unix(0, 0).UTC(), step: time.Second}).
+		Observe(recorder.Observer())
+	builder.From(locked).On(unlock).To(unlocked)
+	builder.From(unlocked).On(lock).To(locked).When("door closed", func(payload) bool { return false })
+	return builder.Build()
+}
+
+func TestAuditRecorder(t *testing.T) {
+	t.Run("records successful and rejected fires with actor and redacted payload", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		sink := &MemoryAuditSink[state, trigger]{}
+		recorder := NewAuditRecorder[state, trigger, payload](sink).
+			WithActor(func(ctx context.Context, _ payload) string { return ctx.Value(actorKey{}).(string) }).
+			WithPayload(func(payload) any { return "redacted" })
+		machine := New(newAuditedSpec(recorder), locked)
+		ctx := context.WithValue(t.Context(), actorKey{}, "alice")
+
+		/* ---------------------------------- When ---------------------------------- */
+		require.NoError(machine.Fire(ctx, unlock, payload{}))
+		err := machine.Fire(ctx, lock, payload{})
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.ErrorIs(err, ErrTransitionRejected)
+		require.Equal([]AuditRecord[state, trigger]{
+			{Time: time.Unix(0, 0).UTC(), Actor: "alice", Trigger: unlock, From: locked, To: unlocked, Result: Transitioned, Payload: "redacted"},
+			{Time: time.Unix(2, 0).UTC(), Actor: "alice", Trigger: lock, From: unlocked, To: unlocked, Result: Rejected, Error: err.Error(), Payload: "redacted"},
+		}, sink.Records())
+	})
+
+	t.Run("can be attached to a single machine", func(t *testing.T) {
+		require := require.New(t)
+
+		sink := &MemoryAuditSink[state, trigger]{}
+		spec := newAuditedSpec(NewAuditRecorder[state, trigger, payload]())
+		audited := New(spec, locked).Observe(NewAuditRecorder[state, trigger, payload](sink).Observer())
+		other := New(spec, locked)
+
+		require.NoError(audited.Fire(t.Context(), unlock, payload{}))
+		require.NoError(other.Fire(t.Context(), unlock, payload{}))
+
+		require.Len(sink.Records(), 1)
+		require.Nil(sink.Records()[0].Payload, "payloads are only recorded with WithPayload")
+	})
+
+	t.Run("reports sink errors", func(t *testing.T) {
+		var reported []error
+		failure := errors.New("disk full")
+		recorder := NewAuditRecorder[state, trigger, payload](failingAuditSink{failure}).
+			OnError(func(_ context.Context, _ AuditRecord[state, trigger], err error) { reported = append(reported, err) })
+
+		require.NoError(t, New(newAuditedSpec(recorder), locked).Fire(t.Context(), unlock, payload{}))
+
+		require.Equal(t, []error{failure}, reported)
+	})
+}
+
+type failingAuditSink struct{ err error }
+
+func (s failingAuditSink) WriteRecord(context.Context, AuditRecord[state, trigger]) error { return s.err }
+
+func TestAuditSinks(t *testing.T) {
+	record := AuditRecord[state, trigger]{
+		Time: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), Actor: "alice", Trigger: lock, From: unlocked, To: unlocked,
+		Result: Rejected, Error: "transition rejected", Payload: map[string]string{"card": "****"},
+	}
+
+	t.Run("json lines", func(t *testing.T) {
+		var buf bytes.Buffer
+		sink := NewJSONLinesAuditSink[state, trigger](&buf)
+
+		require.NoError(t, sink.WriteRecord(t.Context(), record))
+		require.NoError(t, sink.WriteRecord(t.Context(), record))
+
+		line := `{"time":"2026-01-02T03:04:05Z","actor":"alice","trigger":"lock","from":"unlocked","to":"unlocked",` +
+			`"result":"rejected","error":"transition rejected","payload":{"card":"****"}}` + "\n"
+		require.Equal(t, line+line, buf.String())
+	})
+
+	t.Run("slog", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+			ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}))
+
+		require.NoError(t, NewSlogAuditSink[state, trigger](logger).WriteRecord(t.Context(), record))
+
+		require.Equal(t, `level=INFO msg="fsm audit" at=2026-01-02T03:04:05.000Z trigger=lock from=unlocked to=unlocked `+
+			`result=rejected actor=alice error="transition rejected" payload=map[card:****]`, strings.TrimSpace(buf.String()))
+	})
+}
@@ -0,0 +1,54 @@
+package fsm
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is the source of time for time-dependent features such as Timeout and Retry backoff. Inject a fake
+// implementation, e.g. fsmtest.Clock, with Builder.WithClock to make them deterministic in tests.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// AfterFunc calls f in its own goroutine once d has elapsed, unless the returned Timer is stopped first.
+	AfterFunc(d time.Duration, f func()) Timer
+	// Sleep blocks until d has elapsed or ctx is done, returning ctx's error in the latter case.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// Timer is a pending AfterFunc call.
+type Timer interface {
+	// Stop prevents the call from happening. It returns false if the call has already happened or been stopped.
+	Stop() bool
+}
+
+// SystemClock is the Clock backed by the time package. It is the default.
+type SystemClock struct{}
+
+// Now returns time.Now().
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// AfterFunc wraps time.AfterFunc.
+func (SystemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// Sleep blocks until d has elapsed or ctx is done.
+func (SystemClock) Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WithClock sets the Clock used by the spec. Defaults to SystemClock.
+func (b *Builder[S, T, Payload]) WithClock(clock Clock) *Builder[S, T, Payload] {
+	b.clock = clock
+	return b
+}
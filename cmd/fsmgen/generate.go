@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"strconv"
+
+	"github.com/tobbstr/fsm/fsmspec"
+)
+
+// config configures the generated code.
+type config struct {
+	source      string // the document's file name, mentioned in the generated header
+	pkg         string
+	stateType   string
+	triggerType string
+	payloadType string
+	funcName    string
+}
+
+// generate returns the formatted Go source for doc. Besides the problems Document.Validate reports, it reports names
+// that cannot be Go identifiers and states and triggers with the same name, each with the line it is on.
+func generate(doc *fsmspec.Document, c config) ([]byte, error) {
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	g := &generator{config: c, declared: make(map[string]int)}
+	for _, f := range []struct{ flag, name string }{
+		{"package", c.pkg}, {"state", c.stateType}, {"trigger", c.triggerType}, {"payload", c.payloadType}, {"func", c.funcName},
+	} {
+		if !token.IsIdentifier(f.name) {
+			g.fail(0, "-%s %q is not a Go identifier", f.flag, f.name)
+		}
+	}
+	states := make([]fsmspec.Name, len(doc.States))
+	for i, st := range doc.States {
+		states[i] = fsmspec.Name{Value: st.Name, Line: st.Line}
+	}
+	g.declare(states)
+	g.declare(triggers(doc))
+	for _, st := range doc.States {
+		for _, name := range append(append([]string{}, st.OnEntry...), st.OnExit...) {
+			g.reference(st.Line, "action", name)
+		}
+	}
+	for _, t := range doc.Transitions {
+		for _, br := range t.AllBranches() {
+			g.reference(br.Line, "condition", br.When)
+			g.reference(br.Line, "action", br.Do)
+		}
+	}
+	if err := errors.Join(g.errs...); err != nil {
+		return nil, err
+	}
+
+	g.printf("// Code generated by fsmgen from %s; DO NOT EDIT.\n\n", c.source)
+	g.printf("package %s\n\n", c.pkg)
+	g.printf("import (\n\"strconv\"\n\n\"github.com/tobbstr/fsm\"\n)\n\n")
+	g.enum(c.stateType, "state", states)
+	g.enum(c.triggerType, "trigger", triggers(doc))
+	g.wire(doc)
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return src, nil
+}
+
+// triggers returns the declared triggers, or else those used by the transitions, in order of first use.
+func triggers(doc *fsmspec.Document) []fsmspec.Name {
+	if len(doc.Triggers) > 0 {
+		return doc.Triggers
+	}
+	var names []fsmspec.Name
+	seen := make(map[string]bool)
+	for _, t := range doc.Transitions {
+		if !seen[t.On] {
+			seen[t.On] = true
+			names = append(names, fsmspec.Name{Value: t.On, Line: t.Line})
+		}
+	}
+	return names
+}
+
+// generator accumulates the generated source and the problems found on the way.
+type generator struct {
+	config
+	buf      bytes.Buffer
+	declared map[string]int // the line of each generated constant
+	errs     []error
+}
+
+// declare checks that names can be declared as constants.
+func (g *generator) declare(names []fsmspec.Name) {
+	for _, n := range names {
+		if line, dup := g.declared[n.Value]; dup {
+			g.fail(n.Line, "%q is already declared on line %d", n.Value, line)
+			continue
+		}
+		g.declared[n.Value] = n.Line
+		if !token.IsIdentifier(n.Value) {
+			g.fail(n.Line, "%q is not a Go identifier", n.Value)
+		}
+	}
+}
+
+// reference checks that a condition or action name can refer to a Go function.
+func (g *generator) reference(line int, kind, name string) {
+	if name != "" && !token.IsIdentifier(name) {
+		g.fail(line, "%s %q is not a Go identifier", kind, name)
+	}
+}
+
+// enum writes the type called name with a constant per value, in order, and its String method.
+func (g *generator) enum(name, kind string, values []fsmspec.Name) {
+	g.printf("// %s is a %s of the workflow in %s.\n", name, kind, g.source)
+	g.printf("type %s uint\n\n", name)
+	g.printf("const (\n")
+	for i, v := range values {
+		if i == 0 {
+			g.printf("%s %s = iota\n", v.Value, name)
+		} else {
+			g.printf("%s\n", v.Value)
+		}
+	}
+	g.printf(")\n\n")
+	g.printf("// String returns the name of the %s in %s.\n", kind, g.source)
+	g.printf("func (v %s) String() string {\nswitch v {\n", name)
+	for _, v := range values {
+		g.printf("case %s:\nreturn %q\n", v.Value, v.Value)
+	}
+	g.printf("}\nreturn %q + strconv.FormatUint(uint64(v), 10) + \")\"\n}\n\n", name+"(")
+}
+
+// wire writes the function that declares the workflow on a Builder.
+func (g *generator) wire(doc *fsmspec.Document) {
+	g.printf("// %s declares the workflow in %s on b.\n", g.funcName, g.source)
+	g.printf("func %s(b *fsm.Builder[%s, %s, %s]) {\n", g.funcName, g.stateType, g.triggerType, g.payloadType)
+	for _, st := range doc.States {
+		if st.Parent != "" {
+			g.printf("b.From(%s).WithParent(%s)\n", st.Name, st.Parent)
+		}
+		if st.Initial != "" {
+			g.printf("b.From(%s).WithInitial(%s)\n", st.Name, st.Initial)
+		}
+		for _, name := range st.OnEntry {
+			g.printf("b.From(%s).OnEntry(%q, %s)\n", st.Name, name, name)
+		}
+		for _, name := range st.OnExit {
+			g.printf("b.From(%s).OnExit(%q, %s)\n", st.Name, name, name)
+		}
+	}
+	for _, t := range doc.Transitions {
+		switch {
+		case t.Ignore:
+			g.printf("b.From(%s).Ignore(%s)\n", t.From, t.On)
+		case t.Forbid != "":
+			g.printf("b.From(%s).Forbid(%s, %q)\n", t.From, t.On, t.Forbid)
+		default:
+			g.printf("b.From(%s).On(%s)", t.From, t.On)
+			branches := t.AllBranches()
+			for _, br := range branches {
+				sep := "."
+				if len(branches) > 1 {
+					sep = ".\n" // one branch per line
+				}
+				g.printf("%sTo(%s)", sep, br.To)
+				if br.When != "" {
+					g.printf(".When(%q, %s)", br.When, br.When)
+				}
+				if br.Do != "" {
+					g.printf(".Do(%q, %s)", br.Do, br.Do)
+				}
+			}
+			g.printf("\n")
+		}
+	}
+	g.printf("}\n")
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// fail records a problem on line, or with no line if it is 0.
+func (g *generator) fail(line int, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if line > 0 {
+		msg = "line " + strconv.Itoa(line) + ": " + msg
+	}
+	g.errs = append(g.errs, errors.New(msg))
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tobbstr/fsm/fsmspec"
+)
+
+var exampleConfig = config{
+	source:      "order.yaml",
+	pkg:         "example",
+	stateType:   "OrderState",
+	triggerType: "OrderTrigger",
+	payloadType: "OrderPayload",
+	funcName:    "WireOrder",
+}
+
+func TestGenerate(t *testing.T) {
+	t.Run("generates the committed example", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		want, err := os.ReadFile(filepath.Join("internal", "example", "order_fsm.go"))
+		require.NoError(err)
+
+		/* ---------------------------------- When ---------------------------------- */
+		got, err := generateFile(filepath.Join("internal", "example", "order.yaml"), exampleConfig)
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.NoError(err)
+		require.Equal(string(want), string(got), "run go generate ./... to update the example")
+	})
+
+	t.Run("derives triggers from transitions in order of first use", func(t *testing.T) {
+		require := require.New(t)
+
+		doc := parse(t, `
+states: [Created, Shipped]
+transitions:
+  - from: Shipped
+    on: Return
+    to: Created
+  - from: Created
+    on: Ship
+    to: Shipped
+`)
+
+		src, err := generate(doc, exampleConfig)
+
+		require.NoError(err)
+		require.Contains(string(src), "\tReturn OrderTrigger = iota\n\tShip\n)")
+	})
+
+	t.Run("reports every name that is not a Go identifier with its line", func(t *testing.T) {
+		doc := parse(t, `
+states:
+  - Created
+  - name: on hold
+    onEntry: [notify-team]
+triggers: [Pay, Created]
+transitions:
+  - from: Created
+    on: Pay
+    to: on hold
+    when: card.valid
+`)
+
+		_, err := generate(doc, config{pkg: "example", stateType: "OrderState", triggerType: "OrderTrigger", payloadType: "OrderPayload", funcName: "wire order"})
+
+		require.EqualError(t, err, strings.Join([]string{
+			`-func "wire order" is not a Go identifier`,
+			`line 4: "on hold" is not a Go identifier`,
+			`line 6: "Created" is already declared on line 3`,
+			`line 4: action "notify-team" is not a Go identifier`,
+			`line 8: condition "card.valid" is not a Go identifier`,
+		}, "\n"))
+	})
+
+	t.Run("reports validation errors", func(t *testing.T) {
+		doc := parse(t, `
+states: [Created]
+transitions:
+  - from: Created
+    on: Pay
+    to: Paid
+`)
+
+		_, err := generate(doc, exampleConfig)
+
+		require.EqualError(t, err, `line 4: undeclared state "Paid"`)
+	})
+}
+
+func TestRun(t *testing.T) {
+	t.Run("writes the generated file next to the document", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		dir := t.TempDir()
+		in := filepath.Join(dir, "door.yaml")
+		require.NoError(os.WriteFile(in, []byte("states: [Closed, Open]\ntransitions: [{from: Closed, on: Push, to: Open}]\n"), 0o644))
+		var stderr bytes.Buffer
+
+		/* ---------------------------------- When ---------------------------------- */
+		code := run([]string{"-in", in, "-package", "door"}, &stderr)
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.Equal(0, code, stderr.String())
+		src, err := os.ReadFile(filepath.Join(dir, "door_fsm.go"))
+		require.NoError(err)
+		require.Contains(string(src), "package door\n")
+		require.Contains(string(src), "func Wire(b *fsm.Builder[State, Trigger, Payload]) {\n\tb.From(Closed).On(Push).To(Open)\n}")
+	})
+
+	t.Run("reports problems prefixed with the document", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		dir := t.TempDir()
+		in := filepath.Join(dir, "door.yaml")
+		require.NoError(os.WriteFile(in, []byte("states: [Closed]\ntransitions: [{from: Closed, on: Push, to: Open}, {from: Ajar, on: Push, to: Closed}]\n"), 0o644))
+		var stderr bytes.Buffer
+
+		/* ---------------------------------- When ---------------------------------- */
+		code := run([]string{"-in", in, "-package", "door"}, &stderr)
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.Equal(1, code)
+		require.Equal(in+`: line 2: undeclared state "Open"`+"\n"+in+`: line 2: undeclared state "Ajar"`+"\n", stderr.String())
+		require.NoFileExists(filepath.Join(dir, "door_fsm.go"))
+	})
+}
+
+func parse(t *testing.T, doc string) *fsmspec.Document {
+	t.Helper()
+	parsed, err := fsmspec.Parse(strings.NewReader(doc))
+	require.NoError(t, err)
+	return parsed
+}
@@ -0,0 +1,20 @@
+module github.com/tobbstr/fsm/cmd/fsmgen
+
+go 1.24.6
+
+require (
+	github.com/stretchr/testify v1.11.0
+	github.com/tobbstr/fsm v0.0.0
+	github.com/tobbstr/fsm/fsmspec v0.0.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace (
+	github.com/tobbstr/fsm => ../../
+	github.com/tobbstr/fsm/fsmspec => ../../fsmspec
+)
@@ -0,0 +1,28 @@
+// Package example is a workflow generated by fsmgen from order.yaml. It declares the payload type and the conditions
+// and actions the document names; order_fsm.go is generated.
+package example
+
+import "context"
+
+//go:generate go run ../.. -in order.yaml -state OrderState -trigger OrderTrigger -payload OrderPayload -func WireOrder
+
+// OrderPayload is the payload of every Fire.
+type OrderPayload struct {
+	CardValid    bool
+	LabelPrinted bool
+	Log          *[]string // the actions run, in order
+}
+
+func cardValid(p OrderPayload) bool    { return p.CardValid }
+func labelPrinted(p OrderPayload) bool { return p.LabelPrinted }
+
+func chargeCard(_ context.Context, p OrderPayload) error   { return record(p, "chargeCard") }
+func reserveStock(_ context.Context, p OrderPayload) error { return record(p, "reserveStock") }
+func releaseStock(_ context.Context, p OrderPayload) error { return record(p, "releaseStock") }
+
+func record(p OrderPayload, action string) error {
+	if p.Log != nil {
+		*p.Log = append(*p.Log, action)
+	}
+	return nil
+}
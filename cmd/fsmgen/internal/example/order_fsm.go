@@ -0,0 +1,81 @@
+// Code generated by fsmgen from order.yaml; DO NOT EDIT.
+
+package example
+
+import (
+	"strconv"
+
+	"github.com/tobbstr/fsm"
+)
+
+// OrderState is a state of the workflow in order.yaml.
+type OrderState uint
+
+const (
+	Created OrderState = iota
+	Fulfilment
+	Packing
+	Labelling
+	Shipped
+	Rejected
+)
+
+// String returns the name of the state in order.yaml.
+func (v OrderState) String() string {
+	switch v {
+	case Created:
+		return "Created"
+	case Fulfilment:
+		return "Fulfilment"
+	case Packing:
+		return "Packing"
+	case Labelling:
+		return "Labelling"
+	case Shipped:
+		return "Shipped"
+	case Rejected:
+		return "Rejected"
+	}
+	return "OrderState(" + strconv.FormatUint(uint64(v), 10) + ")"
+}
+
+// OrderTrigger is a trigger of the workflow in order.yaml.
+type OrderTrigger uint
+
+const (
+	Pay OrderTrigger = iota
+	Pack
+	Ship
+	Cancel
+)
+
+// String returns the name of the trigger in order.yaml.
+func (v OrderTrigger) String() string {
+	switch v {
+	case Pay:
+		return "Pay"
+	case Pack:
+		return "Pack"
+	case Ship:
+		return "Ship"
+	case Cancel:
+		return "Cancel"
+	}
+	return "OrderTrigger(" + strconv.FormatUint(uint64(v), 10) + ")"
+}
+
+// WireOrder declares the workflow in order.yaml on b.
+func WireOrder(b *fsm.Builder[OrderState, OrderTrigger, OrderPayload]) {
+	b.From(Fulfilment).WithInitial(Packing)
+	b.From(Fulfilment).OnEntry("reserveStock", reserveStock)
+	b.From(Fulfilment).OnExit("releaseStock", releaseStock)
+	b.From(Packing).WithParent(Fulfilment)
+	b.From(Labelling).WithParent(Fulfilment)
+	b.From(Created).On(Pay).
+		To(Fulfilment).When("cardValid", cardValid).Do("chargeCard", chargeCard).
+		To(Rejected)
+	b.From(Packing).On(Pack).To(Labelling)
+	b.From(Fulfilment).On(Ship).To(Shipped).When("labelPrinted", labelPrinted)
+	b.From(Fulfilment).Forbid(Cancel, "already being packed")
+	b.From(Shipped).Ignore(Pay)
+}
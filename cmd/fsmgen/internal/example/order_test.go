@@ -0,0 +1,32 @@
+package example
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tobbstr/fsm"
+)
+
+func TestWireOrder(t *testing.T) {
+	require := require.New(t)
+
+	/* ---------------------------------- Given --------------------------------- */
+	b := fsm.NewBuilder[OrderState, OrderTrigger, OrderPayload]()
+	WireOrder(b)
+	machine := fsm.New(b.Build(), Created)
+	var log []string
+
+	/* ---------------------------------- When ---------------------------------- */
+	require.NoError(machine.Fire(t.Context(), Pay, OrderPayload{CardValid: true, Log: &log}))
+	require.NoError(machine.Fire(t.Context(), Pack, OrderPayload{Log: &log}))
+	require.ErrorIs(machine.Fire(t.Context(), Cancel, OrderPayload{Log: &log}), fsm.ErrTransitionForbidden)
+	require.ErrorIs(machine.Fire(t.Context(), Ship, OrderPayload{Log: &log}), fsm.ErrTransitionRejected)
+	require.NoError(machine.Fire(t.Context(), Ship, OrderPayload{LabelPrinted: true, Log: &log}))
+	require.NoError(machine.Fire(t.Context(), Pay, OrderPayload{Log: &log}))
+
+	/* ---------------------------------- Then ---------------------------------- */
+	require.Equal(Shipped, machine.State())
+	require.Equal([]string{"chargeCard", "reserveStock", "releaseStock"}, log)
+	require.Equal("Labelling", Labelling.String())
+	require.Equal("OrderTrigger(7)", OrderTrigger(7).String())
+}
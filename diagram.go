@@ -0,0 +1,399 @@
+package fsm
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Direction is the layout direction of a diagram.
+type Direction string
+
+// The layout directions; each exporter maps them to its own syntax.
+const (
+	TopToBottom Direction = "TB"
+	LeftToRight Direction = "LR"
+	BottomToTop Direction = "BT"
+	RightToLeft Direction = "RL"
+)
+
+// DiagramOptions configures the diagram exporters: DOT, PlantUML and MermaidJSDiagram. States are named as formatted
+// by %v; names that match no state select nothing. The zero value renders the whole spec in the format's default
+// layout. Output is deterministic: states are ordered by value and edges by source state, trigger and branch.
+type DiagramOptions struct {
+	// Direction sets the layout direction; empty leaves the format's default.
+	Direction Direction
+	// HideDescriptions omits guard, action and hook descriptions, leaving only states and triggers.
+	HideDescriptions bool
+	// HideGuards omits guard descriptions from edge labels.
+	HideGuards bool
+	// HideActions omits action descriptions from edge labels.
+	HideActions bool
+
+	// Focus restricts the diagram to the named state and its substates.
+	Focus string
+	// Reachable restricts the diagram to the states reachable from the named state, including through transitions
+	// declared on its ancestors. Their ancestors are drawn as well, to keep the nesting.
+	Reachable string
+	// MaxHops limits Reachable to states at most that many transitions away; 0 means no limit.
+	MaxHops int
+
+	// Collapse draws the named composite states as simple states. Edges from and to their substates are attached to
+	// them, and edges between their substates are dropped.
+	Collapse []string
+	// CollapseAll collapses every top-level composite state.
+	CollapseAll bool
+	// GroupBranches draws the branches between the same pair of states as one edge, with labels joined by " | ".
+	GroupBranches bool
+
+	// Styles maps state names to CSS styles such as "fill:#f96". Only MermaidJSDiagram applies them.
+	Styles map[string]string
+	// ActiveStyle is the style Machine.MermaidJSDiagram gives the active hierarchy; empty means DefaultActiveStyle.
+	ActiveStyle string
+}
+
+// diagramOptions returns the first of opts, or the zero DiagramOptions.
+func diagramOptions(opts []DiagramOptions) DiagramOptions {
+	if len(opts) == 0 {
+		return DiagramOptions{}
+	}
+	return opts[0]
+}
+
+// diagramEdge is a labelled edge of a diagram.
+type diagramEdge[S ~uint] struct {
+	from, to S
+	label    string
+}
+
+// edgeLabel returns the label "trigger [cond] / action", leaving out what o hides.
+func edgeLabel[T ~uint](o DiagramOptions, trigger T, condDesc, actionDesc string) string {
+	label := fmtName(trigger)
+	if condDesc != "" && !o.HideDescriptions && !o.HideGuards {
+		label += " [" + condDesc + "]"
+	}
+	if actionDesc != "" && !o.HideDescriptions && !o.HideActions {
+		label += " / " + actionDesc
+	}
+	return label
+}
+
+// diagram is the format-independent view of a Spec that the exporters render: its states as a forest, ordered by
+// value, and its edges, ordered by source state, trigger and branch.
+type diagram[S ~uint] struct {
+	roots    []S
+	children map[S][]S
+	parent   map[S]S
+	initial  map[S]S
+	entry    map[S][]string // described entry hooks
+	exit     map[S][]string // described exit hooks
+	edges    []diagramEdge[S]
+}
+
+func newDiagram[S ~uint]() *diagram[S] {
+	return &diagram[S]{
+		children: make(map[S][]S),
+		parent:   make(map[S]S),
+		initial:  make(map[S]S),
+		entry:    make(map[S][]string),
+		exit:     make(map[S][]string),
+	}
+}
+
+// diagram builds the spec's diagram as selected by o.
+func (spec *Spec[S, T, Payload]) diagram(o DiagramOptions) *diagram[S] {
+	return spec.fullDiagram(o).filter(o)
+}
+
+// fullDiagram builds the diagram of the whole spec. A state is included if the spec declares anything about it.
+func (spec *Spec[S, T, Payload]) fullDiagram(o DiagramOptions) *diagram[S] {
+	d := newDiagram[S]()
+	used := make([]bool, spec.stateCount)
+	for from := uint(0); from < spec.stateCount; from++ {
+		for trigger := uint(0); trigger < spec.triggerCount; trigger++ {
+			s := &spec.slots[transitionIndex(S(from), T(trigger), spec.triggerCount)]
+			if s.disposition != undeclared {
+				used[from] = true
+			}
+			if !s.valid {
+				continue
+			}
+			used[from] = true
+			for _, br := range s.all() {
+				used[br.next] = true
+				d.edges = append(d.edges, diagramEdge[S]{
+					from: S(from), to: br.next, label: edgeLabel(o, T(trigger), br.condDesc, br.actionDesc),
+				})
+			}
+		}
+	}
+	for st := uint(0); st < spec.stateCount; st++ {
+		if parent := spec.stateParents[st]; parent != nil {
+			used[st], used[*parent] = true, true
+		}
+		if initial := spec.initialStates[st]; initial != nil {
+			used[st], used[*initial] = true, true
+			d.initial[S(st)] = *initial
+		}
+		hooks := spec.stateHooks[st]
+		if len(hooks.onEntry) > 0 || len(hooks.onExit) > 0 || len(spec.stateTimeouts[st]) > 0 || spec.stateUnhandled[st] != nil {
+			used[st] = true
+		}
+		if o.HideDescriptions {
+			continue
+		}
+		for _, h := range hooks.onEntry {
+			if h.desc != "" {
+				d.entry[S(st)] = append(d.entry[S(st)], h.desc)
+			}
+		}
+		for _, h := range hooks.onExit {
+			if h.desc != "" {
+				d.exit[S(st)] = append(d.exit[S(st)], h.desc)
+			}
+		}
+	}
+	for st := uint(0); st < spec.stateCount; st++ {
+		if !used[st] {
+			continue
+		}
+		if parent := spec.stateParents[st]; parent != nil {
+			d.parent[S(st)] = *parent
+			d.children[*parent] = append(d.children[*parent], S(st))
+		} else {
+			d.roots = append(d.roots, S(st))
+		}
+	}
+	return d
+}
+
+// filter returns the diagram restricted to the states selected by o, with composite states collapsed and branches
+// grouped as o requires.
+func (d *diagram[S]) filter(o DiagramOptions) *diagram[S] {
+	selected := d.selected(o)
+	collapsed := d.collapsed(o)
+	// shown reports whether a state is drawn: it is selected and not inside a collapsed state.
+	shown := func(state S) bool {
+		if selected != nil && !selected[state] {
+			return false
+		}
+		return !slices.ContainsFunc(d.ancestors(state), func(ancestor S) bool { return collapsed[ancestor] })
+	}
+	// drawnAs returns the state an edge end is attached to: the outermost collapsed state containing it, or itself.
+	drawnAs := func(state S) S {
+		drawn := state
+		for _, ancestor := range d.ancestors(state) {
+			if collapsed[ancestor] {
+				drawn = ancestor
+			}
+		}
+		return drawn
+	}
+
+	out := newDiagram[S]()
+	d.walk(func(state S) {
+		if !shown(state) {
+			return
+		}
+		if parent, ok := d.parent[state]; ok && shown(parent) {
+			out.parent[state] = parent
+			out.children[parent] = append(out.children[parent], state)
+		} else {
+			out.roots = append(out.roots, state)
+		}
+		if initial, ok := d.initial[state]; ok && !collapsed[state] && shown(initial) {
+			out.initial[state] = initial
+		}
+		out.entry[state], out.exit[state] = d.entry[state], d.exit[state]
+	})
+	for _, e := range d.edges {
+		if selected != nil && (!selected[e.from] || !selected[e.to]) {
+			continue
+		}
+		from, to := drawnAs(e.from), drawnAs(e.to)
+		if from == to && e.from != e.to {
+			continue // between substates of a collapsed state
+		}
+		if o.GroupBranches {
+			i := slices.IndexFunc(out.edges, func(g diagramEdge[S]) bool { return g.from == from && g.to == to })
+			if i >= 0 {
+				if !slices.Contains(strings.Split(out.edges[i].label, " | "), e.label) {
+					out.edges[i].label += " | " + e.label
+				}
+				continue
+			}
+		}
+		out.edges = append(out.edges, diagramEdge[S]{from: from, to: to, label: e.label})
+	}
+	return out
+}
+
+// selected returns the states selected by o.Focus and o.Reachable, or nil if every state is selected.
+func (d *diagram[S]) selected(o DiagramOptions) map[S]bool {
+	if o.Focus == "" && o.Reachable == "" {
+		return nil
+	}
+	var selected map[S]bool
+	if o.Focus != "" {
+		selected = make(map[S]bool)
+		if focus, ok := d.byName(o.Focus); ok {
+			var add func(state S)
+			add = func(state S) {
+				selected[state] = true
+				for _, child := range d.children[state] {
+					add(child)
+				}
+			}
+			add(focus)
+		}
+	}
+	if o.Reachable != "" {
+		reachable := d.reachable(o.Reachable, o.MaxHops)
+		if selected == nil {
+			return reachable
+		}
+		for state := range selected {
+			if !reachable[state] {
+				delete(selected, state)
+			}
+		}
+	}
+	return selected
+}
+
+// reachable returns the states reachable from the named state within maxHops transitions (0 means no limit), and
+// their ancestors. A transition declared on an ancestor is available from its substates, and entering a composite
+// state enters its initial substates too.
+func (d *diagram[S]) reachable(name string, maxHops int) map[S]bool {
+	reached := make(map[S]bool)
+	start, ok := d.byName(name)
+	if !ok {
+		return reached
+	}
+	var frontier []S
+	enter := func(state S) {
+		for {
+			if !reached[state] {
+				reached[state] = true
+				frontier = append(frontier, state)
+			}
+			initial, ok := d.initial[state]
+			if !ok {
+				return
+			}
+			state = initial
+		}
+	}
+	enter(start)
+	for hops := 0; len(frontier) > 0 && (maxHops <= 0 || hops < maxHops); hops++ {
+		current := frontier
+		frontier = nil
+		for _, state := range current {
+			sources := append([]S{state}, d.ancestors(state)...)
+			for _, e := range d.edges {
+				if slices.Contains(sources, e.from) {
+					enter(e.to)
+				}
+			}
+		}
+	}
+	for state := range reached {
+		for _, ancestor := range d.ancestors(state) {
+			reached[ancestor] = true
+		}
+	}
+	return reached
+}
+
+// collapsed returns the composite states to draw as simple states.
+func (d *diagram[S]) collapsed(o DiagramOptions) map[S]bool {
+	collapsed := make(map[S]bool)
+	for _, name := range o.Collapse {
+		if state, ok := d.byName(name); ok && d.isComposite(state) {
+			collapsed[state] = true
+		}
+	}
+	if o.CollapseAll {
+		for _, root := range d.roots {
+			if d.isComposite(root) {
+				collapsed[root] = true
+			}
+		}
+	}
+	return collapsed
+}
+
+// byName returns the state formatted as name.
+func (d *diagram[S]) byName(name string) (S, bool) {
+	var found S
+	ok := false
+	d.walk(func(state S) {
+		if !ok && fmtName(state) == name {
+			found, ok = state, true
+		}
+	})
+	return found, ok
+}
+
+// ancestors returns the ancestors of state, innermost first.
+func (d *diagram[S]) ancestors(state S) []S {
+	var ancestors []S
+	for {
+		parent, ok := d.parent[state]
+		if !ok {
+			return ancestors
+		}
+		ancestors = append(ancestors, parent)
+		state = parent
+	}
+}
+
+// drawn reports whether the diagram includes state.
+func (d *diagram[S]) drawn(state S) bool {
+	_, nested := d.parent[state]
+	return nested || slices.Contains(d.roots, state)
+}
+
+// isComposite reports whether the state has substates.
+func (d *diagram[S]) isComposite(state S) bool {
+	return len(d.children[state]) > 0
+}
+
+// hasEdge reports whether an edge starts or ends at state.
+func (d *diagram[S]) hasEdge(state S) bool {
+	return slices.ContainsFunc(d.edges, func(e diagramEdge[S]) bool { return e.from == state || e.to == state })
+}
+
+// walk calls visit for every state, depth first, parents before their substates.
+func (d *diagram[S]) walk(visit func(state S)) {
+	var walk func(states []S)
+	walk = func(states []S) {
+		for _, st := range states {
+			visit(st)
+			walk(d.children[st])
+		}
+	}
+	walk(d.roots)
+}
+
+// hookLines returns the described hooks of state as "entry / desc" and "exit / desc" lines.
+func (d *diagram[S]) hookLines(state S) []string {
+	var lines []string
+	for _, desc := range d.entry[state] {
+		lines = append(lines, "entry / "+desc)
+	}
+	for _, desc := range d.exit[state] {
+		lines = append(lines, "exit / "+desc)
+	}
+	return lines
+}
+
+// fmtName returns the %v formatting of a state or trigger.
+func fmtName(v any) string {
+	return fmt.Sprint(v)
+}
+
+// indent returns depth levels of tab indentation.
+func indent(depth int) string {
+	return strings.Repeat("\t", depth)
+}
@@ -0,0 +1,262 @@
+package fsm
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// requireGolden compares got with the golden file testdata/name, or rewrites the file when -update is set.
+func requireGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		require.NoError(t, os.MkdirAll("testdata", 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(got), 0o644))
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err, "run go test -update to create the golden file")
+	require.Equal(t, string(want), got)
+}
+
+type playerState uint
+
+const (
+	stopped playerState = iota
+	active
+	playing
+	paused
+	expired
+)
+
+func (s playerState) String() string {
+	return [...]string{"Stopped", "Active", "Playing", "Paused", "Expired"}[s]
+}
+
+type playerTrigger uint
+
+const (
+	play playerTrigger = iota
+	pause
+	stop
+)
+
+func (t playerTrigger) String() string {
+	return [...]string{"Play", "Pause", "Stop"}[t]
+}
+
+// newPlayerSpec returns a hierarchical spec exercising every diagram feature: a composite state with an initial
+// substate, hooks, multi-branch edges, edges from and to a composite state, and descriptions that need escaping.
+func newPlayerSpec() *Spec[playerState, playerTrigger, payload] {
+	noop := func(context.Context, payload) error { return nil }
+	builder := NewBuilder[playerState, playerTrigger, payload]()
+	builder.From(playing).WithParent(active)
+	builder.From(paused).WithParent(active)
+	builder.From(active).WithInitial(playing).OnExit("release device", noop)
+	builder.From(playing).OnEntry("start audio", noop).OnExit("stop audio", noop)
+	builder.From(stopped).On(play).
+		To(active).When(`license "valid"`, func(payload) bool { return true }).Do("load track", noop).
+		Otherwise(expired)
+	builder.From(playing).On(pause).To(paused)
+	builder.From(paused).On(play).To(playing)
+	builder.From(active).On(stop).To(stopped)
+	return builder.Build()
+}
+
+func TestSpec_DOT(t *testing.T) {
+	noop := func(context.Context, payload) error { return nil }
+	door := NewBuilder[state, trigger, payload]()
+	door.From(locked).On(unlock).To(unlocked).Do("unlatch", noop)
+	door.From(unlocked).On(lock).To(locked).When("door closed", func(payload) bool { return true })
+
+	tests := []struct {
+		name   string
+		golden string
+		dot    string
+	}{
+		{name: "flat", golden: "door.dot", dot: door.Build().DOT()},
+		{name: "hierarchical", golden: "player.dot", dot: newPlayerSpec().DOT()},
+		{
+			name:   "left to right without descriptions",
+			golden: "player_lr_plain.dot",
+			dot:    newPlayerSpec().DOT(DiagramOptions{Direction: LeftToRight, HideDescriptions: true}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requireGolden(t, tt.golden, tt.dot)
+		})
+	}
+}
+
+func TestSpec_PlantUML(t *testing.T) {
+	noop := func(context.Context, payload) error { return nil }
+	door := NewBuilder[state, trigger, payload]()
+	door.From(locked).On(unlock).To(unlocked).Do("unlatch", noop)
+	door.From(unlocked).On(lock).To(locked).When("door closed", func(payload) bool { return true })
+
+	tests := []struct {
+		name     string
+		golden   string
+		plantUML string
+	}{
+		{name: "flat", golden: "door.puml", plantUML: door.Build().PlantUML()},
+		{name: "hierarchical", golden: "player.puml", plantUML: newPlayerSpec().PlantUML()},
+		{
+			name:     "left to right without descriptions",
+			golden:   "player_lr_plain.puml",
+			plantUML: newPlayerSpec().PlantUML(DiagramOptions{Direction: LeftToRight, HideDescriptions: true}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requireGolden(t, tt.golden, tt.plantUML)
+		})
+	}
+}
+
+func TestSpec_PlantUML_AliasesNamesThatAreNotIdentifiers(t *testing.T) {
+	require := require.New(t)
+
+	builder := NewBuilder[state, trigger, payload]()
+	builder.From(locked).On(lock).To(state(7))
+
+	diagram := builder.Build().PlantUML()
+
+	require.Contains(diagram, "state \"state(7)\" as state7\n")
+	require.Contains(diagram, "locked --> state7 : lock\n")
+}
+
+func TestSpec_MermaidJSDiagram_Hierarchical(t *testing.T) {
+	tests := []struct {
+		name    string
+		golden  string
+		diagram string
+	}{
+		{name: "nested composite states", golden: "player.mmd", diagram: newPlayerSpec().MermaidJSDiagram()},
+		{
+			name:   "left to right with styles and without descriptions",
+			golden: "player_lr_styled.mmd",
+			diagram: newPlayerSpec().MermaidJSDiagram(DiagramOptions{
+				Direction:        LeftToRight,
+				HideDescriptions: true,
+				Styles:           map[string]string{"Expired": "fill:#f99", "Stopped": "fill:#ccc", "Paused": "fill:#ccc"},
+			}),
+		},
+		{
+			name:    "machine highlights the active hierarchy",
+			golden:  "player_active.mmd",
+			diagram: New(newPlayerSpec(), paused).MermaidJSDiagram(),
+		},
+		{
+			name:    "machine highlights a collapsed active state",
+			golden:  "player_active_collapsed.mmd",
+			diagram: New(newPlayerSpec(), paused).MermaidJSDiagram(DiagramOptions{Collapse: []string{"Active"}}),
+		},
+		{
+			name:    "machine highlights nothing when the active states are not shown",
+			golden:  "player_focus.mmd",
+			diagram: New(newPlayerSpec(), stopped).MermaidJSDiagram(DiagramOptions{Focus: "Active"}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requireGolden(t, tt.golden, tt.diagram)
+		})
+	}
+}
+
+func TestDiagramOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		golden  string
+		diagram func(spec *Spec[playerState, playerTrigger, payload]) string
+	}{
+		{
+			name:   "focus on a subtree",
+			golden: "player_focus.mmd",
+			diagram: func(spec *Spec[playerState, playerTrigger, payload]) string {
+				return spec.MermaidJSDiagram(DiagramOptions{Focus: "Active"})
+			},
+		},
+		{
+			name:   "reachable within one hop",
+			golden: "player_reachable.puml",
+			diagram: func(spec *Spec[playerState, playerTrigger, payload]) string {
+				return spec.PlantUML(DiagramOptions{Reachable: "Stopped", MaxHops: 1})
+			},
+		},
+		{
+			name:   "reachable through a transition declared on an ancestor",
+			golden: "player_reachable_ancestor.mmd",
+			diagram: func(spec *Spec[playerState, playerTrigger, payload]) string {
+				return spec.MermaidJSDiagram(DiagramOptions{Reachable: "Paused", MaxHops: 1})
+			},
+		},
+		{
+			name:   "collapsed composite state",
+			golden: "player_collapsed.dot",
+			diagram: func(spec *Spec[playerState, playerTrigger, payload]) string {
+				return spec.DOT(DiagramOptions{CollapseAll: true})
+			},
+		},
+		{
+			name:   "grouped branches without guards",
+			golden: "player_grouped.mmd",
+			diagram: func(spec *Spec[playerState, playerTrigger, payload]) string {
+				return spec.MermaidJSDiagram(DiagramOptions{Collapse: []string{"Active"}, GroupBranches: true, HideGuards: true})
+			},
+		},
+		{
+			name:   "unknown focus selects nothing",
+			golden: "player_unknown_focus.puml",
+			diagram: func(spec *Spec[playerState, playerTrigger, payload]) string {
+				return spec.PlantUML(DiagramOptions{Focus: "Rewinding"})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requireGolden(t, tt.golden, tt.diagram(newPlayerSpec()))
+		})
+	}
+}
+
+func TestDiagramOptions_GroupBranches(t *testing.T) {
+	require := require.New(t)
+
+	builder := NewBuilder[playerState, playerTrigger, payload]()
+	builder.From(stopped).On(play).
+		To(playing).When("licensed", func(payload) bool { return true }).
+		To(playing).When("trial", func(payload) bool { return true }).
+		Otherwise(expired)
+	builder.From(paused).On(play).To(playing)
+	builder.From(paused).On(stop).To(playing)
+	spec := builder.Build()
+
+	diagram := spec.MermaidJSDiagram(DiagramOptions{GroupBranches: true})
+
+	require.Contains(diagram, "Stopped --> Playing : Play [licensed] | Play [trial]\n")
+	require.Contains(diagram, "Stopped --> Expired : Play\n")
+	require.Contains(diagram, "Paused --> Playing : Play | Stop\n")
+	require.Contains(spec.MermaidJSDiagram(DiagramOptions{GroupBranches: true, HideGuards: true}), "Stopped --> Playing : Play\n")
+}
+
+func TestDiagramOptions_DeterministicOutput(t *testing.T) {
+	require := require.New(t)
+
+	spec := newPlayerSpec()
+	opts := DiagramOptions{Styles: map[string]string{"Stopped": "fill:#ccc", "Paused": "fill:#ccc", "Expired": "fill:#f99", "Playing": "fill:#9f9"}}
+	first := spec.MermaidJSDiagram(opts)
+
+	for range 20 {
+		require.Equal(first, spec.MermaidJSDiagram(opts))
+		require.Equal(spec.DOT(opts), spec.DOT(opts))
+	}
+}
@@ -0,0 +1,94 @@
+package fsm
+
+import (
+	"strings"
+)
+
+// DOT returns a Graphviz digraph of the Spec. Composite states are drawn as clusters containing their substates, with
+// a point marking the initial substate, and edges are labelled "trigger [guard] / action". At most one DiagramOptions
+// may be passed.
+func (spec *Spec[S, T, Payload]) DOT(opts ...DiagramOptions) string {
+	o := diagramOptions(opts)
+	d := spec.diagram(o)
+	var sb strings.Builder
+	sb.WriteString("digraph fsm {\n")
+	if o.Direction != "" {
+		sb.WriteString("\trankdir=" + string(o.Direction) + ";\n")
+	}
+	sb.WriteString("\tcompound=true;\n")
+	sb.WriteString("\tnode [shape=box, style=rounded];\n")
+	for _, st := range d.roots {
+		writeDOTState(&sb, d, st, 1)
+	}
+	for _, e := range d.edges {
+		attrs := []string{"label=" + dotQuote(e.label)}
+		if d.isComposite(e.from) {
+			attrs = append(attrs, "ltail="+dotCluster(e.from))
+		}
+		if d.isComposite(e.to) {
+			attrs = append(attrs, "lhead="+dotCluster(e.to))
+		}
+		sb.WriteString("\t" + dotID(e.from) + " -> " + dotID(e.to) + " [" + strings.Join(attrs, ", ") + "];\n")
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// writeDOTState writes state as a node or, if it is composite, as a cluster holding an invisible anchor node for edges,
+// its initial-substate marker and its substates.
+func writeDOTState[S ~uint](sb *strings.Builder, d *diagram[S], state S, depth int) {
+	lines := append([]string{fmtName(state)}, d.hookLines(state)...)
+	label := dotLabel(lines)
+	if !d.isComposite(state) {
+		if len(lines) == 1 {
+			sb.WriteString(indent(depth) + dotID(state) + ";\n")
+			return
+		}
+		sb.WriteString(indent(depth) + dotID(state) + " [label=" + label + "];\n")
+		return
+	}
+	sb.WriteString(indent(depth) + "subgraph " + dotCluster(state) + " {\n")
+	sb.WriteString(indent(depth+1) + "label=" + label + ";\n")
+	sb.WriteString(indent(depth+1) + dotID(state) + " [shape=point, style=invis];\n")
+	if initial, ok := d.initial[state]; ok {
+		marker := dotQuote(fmtName(state) + "/initial")
+		sb.WriteString(indent(depth+1) + marker + " [shape=point, width=0.15];\n")
+		attrs := ""
+		if d.isComposite(initial) {
+			attrs = " [lhead=" + dotCluster(initial) + "]"
+		}
+		sb.WriteString(indent(depth+1) + marker + " -> " + dotID(initial) + attrs + ";\n")
+	}
+	for _, child := range d.children[state] {
+		writeDOTState(sb, d, child, depth+1)
+	}
+	sb.WriteString(indent(depth) + "}\n")
+}
+
+// dotID returns the quoted node ID of state.
+func dotID[S ~uint](state S) string {
+	return dotQuote(fmtName(state))
+}
+
+// dotCluster returns the quoted ID of the cluster drawing composite state.
+func dotCluster[S ~uint](state S) string {
+	return dotQuote("cluster_" + fmtName(state))
+}
+
+// dotLabel joins lines into a quoted, centred multi-line label.
+func dotLabel(lines []string) string {
+	quoted := make([]string, len(lines))
+	for i, line := range lines {
+		quoted[i] = dotEscape(line)
+	}
+	return `"` + strings.Join(quoted, `\n`) + `"`
+}
+
+// dotQuote returns s as a quoted DOT string.
+func dotQuote(s string) string {
+	return `"` + dotEscape(s) + `"`
+}
+
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
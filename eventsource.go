@@ -0,0 +1,291 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrReplayDiverged is returned when replayed events do not agree with the spec or with each other, e.g. because an
+// event does not start in the state the previous one ended in, or re-evaluated guards select a different target.
+var ErrReplayDiverged = errors.New("replay diverged")
+
+// Event records one transition of an event-sourced machine: the trigger and payload it was fired with and the states
+// it went from and to. Seq numbers a machine's events from 1 without gaps. Payload must be serializable for logs that
+// persist events.
+type Event[S, T ~uint, Payload any] struct {
+	Seq     uint64    `json:"seq"`
+	Trigger T         `json:"trigger"`
+	Payload Payload   `json:"payload"`
+	From    S         `json:"from"`
+	To      S         `json:"to"`
+	At      time.Time `json:"at"`
+}
+
+// EventLog stores the events of event-sourced machines. Implementations must be safe for concurrent use.
+type EventLog[ID comparable, S, T ~uint, Payload any] interface {
+	// Append stores events after the machine's event with Seq expected (0 if it has none). It returns an error wrapping
+	// ErrVersionConflict, and stores nothing, if the machine's last stored event is not expected.
+	Append(ctx context.Context, id ID, expected uint64, events ...Event[S, T, Payload]) error
+	// Load returns the machine's events with Seq greater than after, in order.
+	Load(ctx context.Context, id ID, after uint64) ([]Event[S, T, Payload], error)
+}
+
+// Replay reconstructs a machine from its events, trusting their recorded outcomes: no guard, action or hook is run. It
+// returns an error wrapping ErrReplayDiverged if the events do not form a chain. The machine starts in the first
+// event's From state.
+func Replay[S, T ~uint, Payload any](spec *Spec[S, T, Payload], events []Event[S, T, Payload]) (*Machine[S, T, Payload], error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("replaying no events: %w", ErrReplayDiverged)
+	}
+	m := New(spec, events[0].From)
+	return m, replay(m, events, false)
+}
+
+// ReplayGuards reconstructs a machine from its events like Replay, but re-evaluates the guards of every transition
+// against the recorded payload and checks that they select the recorded target. Use it to detect events the current
+// spec would no longer produce. Guards must be free of side effects; actions and hooks are still not run.
+func ReplayGuards[S, T ~uint, Payload any](spec *Spec[S, T, Payload], events []Event[S, T, Payload]) (*Machine[S, T, Payload], error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("replaying no events: %w", ErrReplayDiverged)
+	}
+	m := New(spec, events[0].From)
+	return m, replay(m, events, true)
+}
+
+// replay applies events to m. With guards, the target of each event is recomputed with Explain.
+func replay[S, T ~uint, Payload any](m *Machine[S, T, Payload], events []Event[S, T, Payload], guards bool) error {
+	for _, e := range events {
+		if e.From != m.state {
+			return fmt.Errorf("event %d starts in state (%v), but the machine is in state (%v): %w",
+				e.Seq, e.From, m.state, ErrReplayDiverged)
+		}
+		if guards {
+			d := m.Explain(e.Trigger, e.Payload)
+			if !d.Matched {
+				return fmt.Errorf("event %d: trigger (%v) no longer transitions from state (%v): %w",
+					e.Seq, e.Trigger, e.From, ErrReplayDiverged)
+			}
+			target := d.Target
+			if initial := m.spec.initialStates[target]; initial != nil {
+				target = *initial
+			}
+			if target != e.To {
+				return fmt.Errorf("event %d: trigger (%v) from state (%v) now leads to state (%v), recorded (%v): %w",
+					e.Seq, e.Trigger, e.From, target, e.To, ErrReplayDiverged)
+			}
+		}
+		if m.entered != nil {
+			var sourceArr, targetArr [maxDepth]S
+			_, _, target, entryN := m.transitionPath(e.To, &sourceArr, &targetArr)
+			m.markEntered(target[:entryN], nil, e.At)
+		}
+		m.state = e.To
+	}
+	return nil
+}
+
+// SnapshotStore loads and saves machine snapshots by machine ID. EventSourcing uses it to shorten replays.
+// Implementations must be safe for concurrent use.
+type SnapshotStore[ID comparable, S, T ~uint] interface {
+	// Load returns the machine's latest snapshot, or an error wrapping ErrMachineNotFound if it has none.
+	Load(ctx context.Context, id ID) (Snapshot[S, T], error)
+	// Save replaces the machine's snapshot.
+	Save(ctx context.Context, id ID, snapshot Snapshot[S, T]) error
+}
+
+// EventSourcing persists machines created from a spec as logs of their transitions rather than as their current state.
+// Machines are loaded by replaying their events, starting from the latest snapshot if snapshotting is enabled.
+type EventSourcing[ID comparable, S, T ~uint, Payload any] struct {
+	spec      *Spec[S, T, Payload]
+	log       EventLog[ID, S, T, Payload]
+	snapshots SnapshotStore[ID, S, T]
+	every     uint64
+}
+
+// NewEventSourcing creates EventSourcing for machines created from spec, appending their events to log.
+func NewEventSourcing[ID comparable, S, T ~uint, Payload any](
+	spec *Spec[S, T, Payload], log EventLog[ID, S, T, Payload],
+) *EventSourcing[ID, S, T, Payload] {
+	return &EventSourcing[ID, S, T, Payload]{spec: spec, log: log}
+}
+
+// WithSnapshots saves a snapshot to store after every `every` events, so that loading replays at most that many.
+func (es *EventSourcing[ID, S, T, Payload]) WithSnapshots(store SnapshotStore[ID, S, T], every int) *EventSourcing[ID, S, T, Payload] {
+	es.snapshots = store
+	es.every = uint64(max(every, 1))
+	return es
+}
+
+// SourcedMachine is an event-sourced machine. Fire it through SourcedMachine.Fire so that its transitions are appended
+// to the EventLog.
+type SourcedMachine[ID comparable, S, T ~uint, Payload any] struct {
+	*Machine[S, T, Payload]
+	id      ID
+	seq     uint64
+	es      *EventSourcing[ID, S, T, Payload]
+	pending []Event[S, T, Payload] // transitions recorded by the observer during the current Fire
+}
+
+// New returns a machine in the initial state with no events. Nothing is stored until it transitions, so Load reports
+// ErrMachineNotFound for it until then.
+func (es *EventSourcing[ID, S, T, Payload]) New(id ID, initialState S) *SourcedMachine[ID, S, T, Payload] {
+	return es.source(id, New(es.spec, initialState), 0)
+}
+
+// Load restores the machine with the ID from its latest snapshot, if any, and replays the events recorded after it
+// with Replay. It returns an error wrapping ErrMachineNotFound if the machine has neither a snapshot nor events.
+func (es *EventSourcing[ID, S, T, Payload]) Load(ctx context.Context, id ID) (*SourcedMachine[ID, S, T, Payload], error) {
+	var m *Machine[S, T, Payload]
+	var seq uint64
+	if es.snapshots != nil {
+		snapshot, err := es.snapshots.Load(ctx, id)
+		switch {
+		case err == nil:
+			m, seq = Restore(es.spec, snapshot), snapshot.Seq
+		case !errors.Is(err, ErrMachineNotFound):
+			return nil, fmt.Errorf("loading snapshot of machine %v: %w", id, err)
+		}
+	}
+	events, err := es.log.Load(ctx, id, seq)
+	if err != nil {
+		return nil, fmt.Errorf("loading events of machine %v: %w", id, err)
+	}
+	if m == nil {
+		if len(events) == 0 {
+			return nil, fmt.Errorf("loading machine %v: %w", id, ErrMachineNotFound)
+		}
+		m = New(es.spec, events[0].From)
+	}
+	if err := replay(m, events, false); err != nil {
+		return nil, fmt.Errorf("replaying machine %v: %w", id, err)
+	}
+	if len(events) > 0 {
+		seq = events[len(events)-1].Seq
+	}
+	return es.source(id, m, seq), nil
+}
+
+func (es *EventSourcing[ID, S, T, Payload]) source(id ID, m *Machine[S, T, Payload], seq uint64) *SourcedMachine[ID, S, T, Payload] {
+	sm := &SourcedMachine[ID, S, T, Payload]{Machine: m, id: id, seq: seq, es: es}
+	m.Observe(Observer[S, T, Payload]{OnTransition: sm.onTransition})
+	return sm
+}
+
+// ID returns the machine's ID.
+func (sm *SourcedMachine[ID, S, T, Payload]) ID() ID {
+	return sm.id
+}
+
+// Seq returns the sequence number of the machine's last event, 0 if it has none.
+func (sm *SourcedMachine[ID, S, T, Payload]) Seq() uint64 {
+	return sm.seq
+}
+
+// Snapshot returns the machine's runtime state, including the sequence number of its last event.
+func (sm *SourcedMachine[ID, S, T, Payload]) Snapshot() Snapshot[S, T] {
+	snapshot := sm.Machine.Snapshot()
+	snapshot.Seq = sm.seq
+	return snapshot
+}
+
+// Fire fires the trigger on the machine and, if it transitions, appends the transition to the EventLog and saves a
+// snapshot when one is due. If appending fails — with ErrVersionConflict when the machine was changed concurrently —
+// the transition has run but is not recorded; discard the machine and load it again. A snapshot error is returned
+// after the event has been recorded.
+func (sm *SourcedMachine[ID, S, T, Payload]) Fire(ctx context.Context, trigger T, payload Payload) error {
+	sm.pending = sm.pending[:0]
+	if err := sm.Machine.Fire(ctx, trigger, payload); err != nil {
+		return err
+	}
+	if len(sm.pending) == 0 {
+		return nil // ignored or handled without transitioning
+	}
+	if err := sm.es.log.Append(ctx, sm.id, sm.seq, sm.pending...); err != nil {
+		return fmt.Errorf("appending events of machine %v: %w", sm.id, err)
+	}
+	previous := sm.seq
+	sm.seq = sm.pending[len(sm.pending)-1].Seq
+	if es := sm.es; es.snapshots != nil && sm.seq/es.every != previous/es.every {
+		if err := es.snapshots.Save(ctx, sm.id, sm.Snapshot()); err != nil {
+			return fmt.Errorf("saving snapshot of machine %v: %w", sm.id, err)
+		}
+	}
+	return nil
+}
+
+func (sm *SourcedMachine[ID, S, T, Payload]) onTransition(_ context.Context, event TransitionEvent[S, T, Payload]) {
+	sm.pending = append(sm.pending, Event[S, T, Payload]{
+		Seq:     sm.seq + uint64(len(sm.pending)) + 1,
+		Trigger: event.Trigger,
+		Payload: event.Payload,
+		From:    event.From,
+		To:      event.To,
+		At:      sm.es.spec.clock.Now(),
+	})
+}
+
+// MemoryEventLog is an in-memory EventLog, for tests and single-process use.
+type MemoryEventLog[ID comparable, S, T ~uint, Payload any] struct {
+	mu     sync.Mutex
+	events map[ID][]Event[S, T, Payload]
+}
+
+// NewMemoryEventLog creates an empty MemoryEventLog.
+func NewMemoryEventLog[ID comparable, S, T ~uint, Payload any]() *MemoryEventLog[ID, S, T, Payload] {
+	return &MemoryEventLog[ID, S, T, Payload]{events: make(map[ID][]Event[S, T, Payload])}
+}
+
+// Append stores the events if the machine's last event is expected.
+func (l *MemoryEventLog[ID, S, T, Payload]) Append(_ context.Context, id ID, expected uint64, events ...Event[S, T, Payload]) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if current := uint64(len(l.events[id])); current != expected {
+		return fmt.Errorf("expected event %d, last stored event is %d: %w", expected, current, ErrVersionConflict)
+	}
+	l.events[id] = append(l.events[id], events...)
+	return nil
+}
+
+// Load returns the machine's events after the given sequence number.
+func (l *MemoryEventLog[ID, S, T, Payload]) Load(_ context.Context, id ID, after uint64) ([]Event[S, T, Payload], error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	events := l.events[id]
+	return slices.Clone(events[min(after, uint64(len(events))):]), nil
+}
+
+// MemorySnapshotStore is an in-memory SnapshotStore, for tests and single-process use.
+type MemorySnapshotStore[ID comparable, S, T ~uint] struct {
+	mu        sync.Mutex
+	snapshots map[ID]Snapshot[S, T]
+}
+
+var _ SnapshotStore[string, uint, uint] = (*MemorySnapshotStore[string, uint, uint])(nil)
+
+// NewMemorySnapshotStore creates an empty MemorySnapshotStore.
+func NewMemorySnapshotStore[ID comparable, S, T ~uint]() *MemorySnapshotStore[ID, S, T] {
+	return &MemorySnapshotStore[ID, S, T]{snapshots: make(map[ID]Snapshot[S, T])}
+}
+
+// Load returns the machine's latest snapshot.
+func (s *MemorySnapshotStore[ID, S, T]) Load(_ context.Context, id ID) (Snapshot[S, T], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot, ok := s.snapshots[id]
+	if !ok {
+		return snapshot, fmt.Errorf("loading snapshot of machine %v: %w", id, ErrMachineNotFound)
+	}
+	return snapshot, nil
+}
+
+// Save replaces the machine's snapshot.
+func (s *MemorySnapshotStore[ID, S, T]) Save(_ context.Context, id ID, snapshot Snapshot[S, T]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots[id] = snapshot
+	return nil
+}
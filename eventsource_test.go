@@ -0,0 +1,174 @@
+package fsm_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tobbstr/fsm"
+	"github.com/tobbstr/fsm/fsmtest"
+)
+
+// newSourcedOrderSpec counts action runs in actions, so tests can check that replaying runs none.
+func newSourcedOrderSpec(actions *int) *fsm.Spec[orderState, orderTrigger, orderPayload] {
+	b := fsm.NewBuilder[orderState, orderTrigger, orderPayload]().WithClock(fsmtest.NewClock(time.Unix(0, 0)))
+	count := func(context.Context, orderPayload) error { *actions++; return nil }
+	b.From(awaitingOrderPayment).On(remind).To(awaitingOrderPayment).Do("send reminder", count)
+	b.From(awaitingOrderPayment).On(payOrder).To(orderPaid).
+		When("not fraudulent", func(p orderPayload) bool { return p.reason != "fraud" }).Do("charge", count)
+	b.From(orderPaid).On(closeOrder).To(orderExpired).Do("archive", count)
+	return b.Build()
+}
+
+// recordingEventLog records the after argument of every Load.
+type recordingEventLog struct {
+	*fsm.MemoryEventLog[string, orderState, orderTrigger, orderPayload]
+	afters []uint64
+}
+
+func (l *recordingEventLog) Load(ctx context.Context, id string, after uint64) ([]fsm.Event[orderState, orderTrigger, orderPayload], error) {
+	l.afters = append(l.afters, after)
+	return l.MemoryEventLog.Load(ctx, id, after)
+}
+
+func TestEventSourcing(t *testing.T) {
+	t.Run("appends transitions and rebuilds machines without running actions", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		actions := 0
+		log := fsm.NewMemoryEventLog[string, orderState, orderTrigger, orderPayload]()
+		es := fsm.NewEventSourcing(newSourcedOrderSpec(&actions), log)
+		machine := es.New("order-1", awaitingOrderPayment)
+
+		/* ---------------------------------- When ---------------------------------- */
+		require.NoError(machine.Fire(t.Context(), remind, orderPayload{}))
+		require.NoError(machine.Fire(t.Context(), payOrder, orderPayload{reason: "card"}))
+		loaded, err := es.Load(t.Context(), "order-1")
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.NoError(err)
+		require.Equal(2, actions, "loading must not re-run actions")
+		require.Equal(orderPaid, loaded.State())
+		require.Equal(uint64(2), loaded.Seq())
+		events, err := log.Load(t.Context(), "order-1", 0)
+		require.NoError(err)
+		require.Equal([]fsm.Event[orderState, orderTrigger, orderPayload]{
+			{Seq: 1, Trigger: remind, From: awaitingOrderPayment, To: awaitingOrderPayment, At: time.Unix(0, 0)},
+			{Seq: 2, Trigger: payOrder, Payload: orderPayload{reason: "card"}, From: awaitingOrderPayment, To: orderPaid, At: time.Unix(0, 0)},
+		}, events)
+	})
+
+	t.Run("loads from the latest snapshot and replays only later events", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		actions := 0
+		log := &recordingEventLog{MemoryEventLog: fsm.NewMemoryEventLog[string, orderState, orderTrigger, orderPayload]()}
+		snapshots := fsm.NewMemorySnapshotStore[string, orderState, orderTrigger]()
+		es := fsm.NewEventSourcing(newSourcedOrderSpec(&actions), log).WithSnapshots(snapshots, 2)
+		machine := es.New("order-1", awaitingOrderPayment)
+		for _, trigger := range []orderTrigger{remind, remind, payOrder} {
+			require.NoError(machine.Fire(t.Context(), trigger, orderPayload{}))
+		}
+
+		/* ---------------------------------- When ---------------------------------- */
+		loaded, err := es.Load(t.Context(), "order-1")
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.NoError(err)
+		require.Equal(orderPaid, loaded.State())
+		require.Equal(uint64(3), loaded.Seq())
+		snapshot, err := snapshots.Load(t.Context(), "order-1")
+		require.NoError(err)
+		require.Equal(fsm.Snapshot[orderState, orderTrigger]{State: awaitingOrderPayment, Seq: 2}, snapshot)
+		require.Equal([]uint64{2}, log.afters)
+	})
+
+	t.Run("rejects appends from a stale machine", func(t *testing.T) {
+		require := require.New(t)
+
+		actions := 0
+		es := fsm.NewEventSourcing(newSourcedOrderSpec(&actions), fsm.NewMemoryEventLog[string, orderState, orderTrigger, orderPayload]())
+		require.NoError(es.New("order-1", awaitingOrderPayment).Fire(t.Context(), remind, orderPayload{}))
+		first, err := es.Load(t.Context(), "order-1")
+		require.NoError(err)
+		second, err := es.Load(t.Context(), "order-1")
+		require.NoError(err)
+
+		require.NoError(first.Fire(t.Context(), payOrder, orderPayload{}))
+		err = second.Fire(t.Context(), remind, orderPayload{})
+
+		require.ErrorIs(err, fsm.ErrVersionConflict)
+	})
+
+	t.Run("reports machines without events", func(t *testing.T) {
+		actions := 0
+		es := fsm.NewEventSourcing(newSourcedOrderSpec(&actions), fsm.NewMemoryEventLog[string, orderState, orderTrigger, orderPayload]())
+
+		_, err := es.Load(t.Context(), "order-1")
+
+		require.ErrorIs(t, err, fsm.ErrMachineNotFound)
+	})
+}
+
+func TestReplay(t *testing.T) {
+	type event = fsm.Event[orderState, orderTrigger, orderPayload]
+	actions := 0
+	spec := newSourcedOrderSpec(&actions)
+
+	t.Run("trusts recorded outcomes", func(t *testing.T) {
+		require := require.New(t)
+
+		machine, err := fsm.Replay(spec, []event{
+			{Seq: 1, Trigger: payOrder, Payload: orderPayload{reason: "fraud"}, From: awaitingOrderPayment, To: orderPaid},
+			{Seq: 2, Trigger: closeOrder, From: orderPaid, To: orderExpired},
+		})
+
+		require.NoError(err)
+		require.Equal(orderExpired, machine.State())
+		require.Zero(actions)
+	})
+
+	t.Run("detects broken chains", func(t *testing.T) {
+		_, err := fsm.Replay(spec, []event{
+			{Seq: 1, Trigger: remind, From: awaitingOrderPayment, To: awaitingOrderPayment},
+			{Seq: 2, Trigger: closeOrder, From: orderPaid, To: orderExpired},
+		})
+
+		require.ErrorIs(t, err, fsm.ErrReplayDiverged)
+	})
+
+	t.Run("with guards detects events the spec no longer produces", func(t *testing.T) {
+		require := require.New(t)
+
+		events := []event{{Seq: 1, Trigger: payOrder, Payload: orderPayload{reason: "card"}, From: awaitingOrderPayment, To: orderPaid}}
+		machine, err := fsm.ReplayGuards(spec, events)
+		require.NoError(err)
+		require.Equal(orderPaid, machine.State())
+
+		events[0].Payload.reason = "fraud"
+		_, err = fsm.ReplayGuards(spec, events)
+		require.ErrorIs(err, fsm.ErrReplayDiverged)
+
+		events[0].Payload.reason, events[0].To = "card", orderExpired
+		_, err = fsm.ReplayGuards(spec, events)
+		require.ErrorIs(err, fsm.ErrReplayDiverged)
+		require.Zero(actions)
+	})
+}
+
+func TestMemorySnapshotStore(t *testing.T) {
+	require := require.New(t)
+
+	store := fsm.NewMemorySnapshotStore[string, orderState, orderTrigger]()
+	_, err := store.Load(t.Context(), "order-1")
+	require.ErrorIs(err, fsm.ErrMachineNotFound)
+
+	require.NoError(store.Save(t.Context(), "order-1", fsm.Snapshot[orderState, orderTrigger]{State: orderPaid, Seq: 3}))
+	snapshot, err := store.Load(t.Context(), "order-1")
+
+	require.NoError(err)
+	require.Equal(fsm.Snapshot[orderState, orderTrigger]{State: orderPaid, Seq: 3}, snapshot)
+}
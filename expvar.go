@@ -0,0 +1,68 @@
+package fsm
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"sync"
+)
+
+// ExpvarName is the name of the expvar.Map that TransitionCounter publishes, served as JSON by the expvar handler at
+// /debug/vars. It maps each spec's name to a map of transition counts.
+const ExpvarName = "fsm_transitions"
+
+var (
+	expvarOnce  sync.Once
+	expvarMu    sync.Mutex // serializes creating a spec's counts map
+	expvarSpecs *expvar.Map
+)
+
+// TransitionCounter counts completed transitions in the expvar.Map published as ExpvarName, under the spec's name.
+// Each transition is keyed "From -Trigger-> To", using the %v formatting of states and triggers. Attach it to a
+// Builder or a Machine with Observe(counter.Observer()).
+type TransitionCounter[S, T ~uint, Payload any] struct {
+	counts *expvar.Map
+}
+
+// NewTransitionCounter creates a TransitionCounter for the spec called name. Counters created with the same name
+// share their counts.
+func NewTransitionCounter[S, T ~uint, Payload any](name string) *TransitionCounter[S, T, Payload] {
+	expvarOnce.Do(func() { expvarSpecs = expvar.NewMap(ExpvarName) })
+	return &TransitionCounter[S, T, Payload]{counts: specCounts(name)}
+}
+
+// specCounts returns the counts map of the spec called name, creating it if needed.
+func specCounts(name string) *expvar.Map {
+	if existing, loaded := expvarSpecs.Get(name).(*expvar.Map); loaded {
+		return existing
+	}
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	if existing, loaded := expvarSpecs.Get(name).(*expvar.Map); loaded {
+		return existing
+	}
+	counts := new(expvar.Map)
+	expvarSpecs.Set(name, counts)
+	return counts
+}
+
+// Observer returns the observer that feeds the counter.
+func (c *TransitionCounter[S, T, Payload]) Observer() Observer[S, T, Payload] {
+	return Observer[S, T, Payload]{OnTransition: c.count}
+}
+
+// Count returns how many From -Trigger-> To transitions were counted.
+func (c *TransitionCounter[S, T, Payload]) Count(from S, trigger T, to S) int64 {
+	if v, ok := c.counts.Get(transitionKey(from, trigger, to)).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func (c *TransitionCounter[S, T, Payload]) count(_ context.Context, event TransitionEvent[S, T, Payload]) {
+	c.counts.Add(transitionKey(event.From, event.Trigger, event.To), 1)
+}
+
+func transitionKey[S, T ~uint](from S, trigger T, to S) string {
+	return fmt.Sprintf("%v -%v-> %v", from, trigger, to)
+}
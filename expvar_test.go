@@ -0,0 +1,49 @@
+package fsm
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransitionCounter(t *testing.T) {
+	t.Run("publishes transition counts per spec", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		counter := NewTransitionCounter[state, trigger, payload]("door")
+		builder := NewBuilder[state, trigger, payload]().Observe(counter.Observer())
+		builder.From(locked).On(unlock).To(unlocked)
+		builder.From(unlocked).On(lock).To(locked)
+		machine := New(builder.Build(), locked)
+
+		/* ---------------------------------- When ---------------------------------- */
+		require.NoError(machine.Fire(t.Context(), unlock, payload{}))
+		require.NoError(machine.Fire(t.Context(), lock, payload{}))
+		require.NoError(machine.Fire(t.Context(), unlock, payload{}))
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.EqualValues(2, counter.Count(locked, unlock, unlocked))
+		require.EqualValues(1, counter.Count(unlocked, lock, locked))
+		require.Zero(counter.Count(unlocked, unlock, locked))
+
+		var published map[string]map[string]int64
+		require.NoError(json.Unmarshal([]byte(expvar.Get(ExpvarName).String()), &published))
+		require.Equal(map[string]int64{"locked -unlock-> unlocked": 2, "unlocked -lock-> locked": 1}, published["door"])
+	})
+
+	t.Run("counters with the same name share counts", func(t *testing.T) {
+		require := require.New(t)
+
+		first := NewTransitionCounter[state, trigger, payload]("shared")
+		second := NewTransitionCounter[state, trigger, payload]("shared")
+		builder := NewBuilder[state, trigger, payload]().Observe(first.Observer()).Observe(second.Observer())
+		builder.From(locked).On(unlock).To(unlocked)
+
+		require.NoError(New(builder.Build(), locked).Fire(t.Context(), unlock, payload{}))
+
+		require.EqualValues(2, second.Count(locked, unlock, unlocked))
+	})
+}
@@ -81,20 +81,43 @@ import (
 	"fmt"
 	"slices"
 	"strings"
+	"time"
 )
 
 const maxDepth = 10 // Needed constraint to allow zero-allocation fsm.Fire(...) runs.
 
 var (
-	ErrNotFound           = fmt.Errorf("not found")
-	ErrTransitionRejected = fmt.Errorf("transition rejected")
+	ErrNotFound            = fmt.Errorf("not found")
+	ErrTransitionRejected  = fmt.Errorf("transition rejected")
+	ErrTransitionForbidden = fmt.Errorf("transition forbidden")
 )
 
+// CanceledError is returned by Fire when its context is done at a phase boundary. Phase is the phase that was about to
+// start and did not run.
+type CanceledError struct {
+	Phase Phase
+	Err   error // the context's error: context.Canceled or context.DeadlineExceeded
+}
+
+// Error describes the cancellation.
+func (e *CanceledError) Error() string {
+	return fmt.Sprintf("context done before %v phase: %v", e.Phase, e.Err)
+}
+
+// Unwrap returns the context's error.
+func (e *CanceledError) Unwrap() error {
+	return e.Err
+}
+
 type (
 	// Condition is a predicate that determines whether a branch is taken.
 	Condition[Payload any] func(payload Payload) bool
 	// Action is a function that performs an action when a transition occurs.
 	Action[Payload any] func(ctx context.Context, payload Payload) error
+	// UnhandledHandler is called by Fire when no level of the active hierarchy defines a transition or declaration for
+	// the trigger. state is the machine's current state. Its return value is returned by Fire, so it can log and
+	// return nil to swallow the event, convert it to a domain error, or return an error wrapping ErrNotFound.
+	UnhandledHandler[S, T ~uint, Payload any] func(ctx context.Context, state S, trigger T, payload Payload) error
 )
 
 // branch is one candidate transition within a (from, trigger) group.
@@ -106,13 +129,26 @@ type branch[S ~uint, Payload any] struct {
 	actionDesc string
 }
 
+// disposition is an explicit, branch-less decision declared for a (state, trigger) via Ignore or Forbid.
+type disposition uint8
+
+const (
+	undeclared disposition = iota
+	ignored                // trigger is deliberately a no-op in the state
+	forbidden              // trigger is rejected in the state and must not bubble to the parent
+)
+
 // slot holds all branches for one (from, trigger), in definition order.
 // Inline first keeps the overwhelmingly common single-branch case allocation-free;
 // more is nil unless the group actually has multiple branches.
+//
+// A slot with a disposition other than undeclared never has branches (valid is false); Build rejects mixing the two.
 type slot[S ~uint, Payload any] struct {
-	valid bool
-	first branch[S, Payload]
-	more  []branch[S, Payload] // nil for single-branch groups
+	valid       bool
+	first       branch[S, Payload]
+	more        []branch[S, Payload] // nil for single-branch groups
+	disposition disposition
+	reason      string // set for forbidden
 }
 
 // match returns the first branch whose condition is nil or returns true, else nil. No allocation.
@@ -144,11 +180,29 @@ type StateHooks[Payload any] struct {
 	OnExit  Action[Payload]
 }
 
+// hook is one registered OnEntry or OnExit action together with its description.
+type hook[Payload any] struct {
+	action Action[Payload]
+	desc   string
+}
+
+// stateHooks holds every hook registered for one state, in registration order.
+type stateHooks[Payload any] struct {
+	onEntry []hook[Payload]
+	onExit  []hook[Payload]
+}
+
 // Builder builds FSM specifications. Create one with NewBuilder.
 type Builder[S, T ~uint, Payload any] struct {
 	branchDefs    []*branchDef[S, T, Payload]
+	dispositions  []dispositionDef[S, T]
 	onSteps       []*onStep[S, T, Payload]
 	stateBuilders []*stateBuilder[S, T, Payload]
+	unhandled     UnhandledHandler[S, T, Payload]
+	middleware    []Middleware[S, T, Payload]
+	recoverPanics bool
+	clock         Clock
+	observers     []Observer[S, T, Payload]
 }
 
 // NewBuilder creates a new Builder used for building FSM specifications which define the states, triggers
@@ -161,6 +215,13 @@ func NewBuilder[S, T ~uint, Payload any]() *Builder[S, T, Payload] {
 	return &Builder[S, T, Payload]{}
 }
 
+// OnUnhandled sets the spec-wide handler Fire calls instead of returning ErrNotFound. It is used only when no state in
+// the active hierarchy has its own handler set with fromStep.OnUnhandled.
+func (b *Builder[S, T, Payload]) OnUnhandled(handler UnhandledHandler[S, T, Payload]) *Builder[S, T, Payload] {
+	b.unhandled = handler
+	return b
+}
+
 // branchDef accumulates the fields for one branch in definition order.
 type branchDef[S, T ~uint, Payload any] struct {
 	from       S
@@ -171,6 +232,15 @@ type branchDef[S, T ~uint, Payload any] struct {
 	action     Action[Payload]
 	actionDesc string
 	isDefault  bool // set by Otherwise
+	resilience resilience
+}
+
+// dispositionDef records an Ignore or Forbid declaration.
+type dispositionDef[S, T ~uint] struct {
+	state       S
+	trigger     T
+	disposition disposition
+	reason      string
 }
 
 // onStep tracks that an On() call was made and whether a To() completed it.
@@ -200,18 +270,46 @@ func (b *Builder[S, T, Payload]) From(state S) *fromStep[S, T, Payload] {
 	return &fromStep[S, T, Payload]{b: b, from: state}
 }
 
-// WithHooks sets the OnEntry and OnExit hooks for the state being defined.
+// WithHooks adds the OnEntry and OnExit hooks for the state being defined, without descriptions. Like OnEntry and
+// OnExit it is additive: hooks from earlier calls are kept and run first.
 func (fs *fromStep[S, T, Payload]) WithHooks(hooks StateHooks[Payload]) *fromStep[S, T, Payload] {
 	sb := &stateBuilder[S, T, Payload]{
 		b:          fs.b,
 		state:      fs.from,
-		hooks:      hooks,
+		onEntry:    hook[Payload]{action: hooks.OnEntry},
+		onExit:     hook[Payload]{action: hooks.OnExit},
 		isHooksSet: true,
 	}
 	fs.b.stateBuilders = append(fs.b.stateBuilders, sb)
 	return fs
 }
 
+// OnEntry adds a hook, and its description, that runs when the state being defined is entered. Hooks run in
+// registration order. The hook can be modified with Timeout and Retry.
+func (fs *fromStep[S, T, Payload]) OnEntry(desc string, action func(ctx context.Context, in Payload) error) *hookStep[S, T, Payload] {
+	sb := &stateBuilder[S, T, Payload]{
+		b:          fs.b,
+		state:      fs.from,
+		onEntry:    hook[Payload]{action: action, desc: desc},
+		isHooksSet: true,
+	}
+	fs.b.stateBuilders = append(fs.b.stateBuilders, sb)
+	return &hookStep[S, T, Payload]{fromStep: fs, sb: sb}
+}
+
+// OnExit adds a hook, and its description, that runs when the state being defined is exited. Hooks run in
+// registration order. The hook can be modified with Timeout and Retry.
+func (fs *fromStep[S, T, Payload]) OnExit(desc string, action func(ctx context.Context, in Payload) error) *hookStep[S, T, Payload] {
+	sb := &stateBuilder[S, T, Payload]{
+		b:          fs.b,
+		state:      fs.from,
+		onExit:     hook[Payload]{action: action, desc: desc},
+		isHooksSet: true,
+	}
+	fs.b.stateBuilders = append(fs.b.stateBuilders, sb)
+	return &hookStep[S, T, Payload]{fromStep: fs, sb: sb}
+}
+
 // WithParent sets the parent state for hierarchical state machines.
 func (fs *fromStep[S, T, Payload]) WithParent(parent S) *fromStep[S, T, Payload] {
 	sb := &stateBuilder[S, T, Payload]{
@@ -236,6 +334,43 @@ func (fs *fromStep[S, T, Payload]) WithInitial(initial S) *fromStep[S, T, Payloa
 	return fs
 }
 
+// Ignore declares the trigger as deliberately handled by doing nothing in the state being defined. Firing it returns
+// nil without changing state or running any hooks, and the trigger does not bubble to the parent state.
+func (fs *fromStep[S, T, Payload]) Ignore(trigger T) *fromStep[S, T, Payload] {
+	fs.b.dispositions = append(fs.b.dispositions, dispositionDef[S, T]{
+		state:       fs.from,
+		trigger:     trigger,
+		disposition: ignored,
+	})
+	return fs
+}
+
+// Forbid declares the trigger as rejected in the state being defined. Firing it returns an ErrTransitionForbidden
+// error carrying the given reason, and the trigger does not bubble to the parent state.
+func (fs *fromStep[S, T, Payload]) Forbid(trigger T, reason string) *fromStep[S, T, Payload] {
+	fs.b.dispositions = append(fs.b.dispositions, dispositionDef[S, T]{
+		state:       fs.from,
+		trigger:     trigger,
+		disposition: forbidden,
+		reason:      reason,
+	})
+	return fs
+}
+
+// OnUnhandled sets the handler Fire calls instead of returning ErrNotFound when the state being defined, or any of
+// its descendants, is active and the trigger is handled nowhere in the hierarchy. The handler of the deepest state in
+// the active hierarchy wins; the Builder-level handler is the fallback.
+func (fs *fromStep[S, T, Payload]) OnUnhandled(handler UnhandledHandler[S, T, Payload]) *fromStep[S, T, Payload] {
+	sb := &stateBuilder[S, T, Payload]{
+		b:              fs.b,
+		state:          fs.from,
+		unhandled:      handler,
+		isUnhandledSet: true,
+	}
+	fs.b.stateBuilders = append(fs.b.stateBuilders, sb)
+	return fs
+}
+
 // On sets the trigger for the transition group.
 func (fs *fromStep[S, T, Payload]) On(trigger T) *onStep[S, T, Payload] {
 	os := &onStep[S, T, Payload]{b: fs.b, from: fs.from, trigger: trigger}
@@ -304,8 +439,17 @@ func (b *Builder[S, T, Payload]) Build() *Spec[S, T, Payload] {
 			maxTrigger = uint(def.trigger)
 		}
 	}
+	for _, def := range b.dispositions {
+		noteState(def.state)
+		if uint(def.trigger) > maxTrigger {
+			maxTrigger = uint(def.trigger)
+		}
+	}
 	for _, sb := range b.stateBuilders {
 		noteState(sb.state)
+		if sb.isTimeoutSet && uint(sb.timeout.trigger) > maxTrigger {
+			maxTrigger = uint(sb.timeout.trigger)
+		}
 		if sb.isParentSet {
 			noteState(sb.parent)
 		}
@@ -319,18 +463,33 @@ func (b *Builder[S, T, Payload]) Build() *Spec[S, T, Payload] {
 	triggerCount := maxTrigger + 1
 
 	slots := make([]slot[S, Payload], stateCount*triggerCount)
-	stateHooks := make([]StateHooks[Payload], stateCount)
+	hooks := make([]stateHooks[Payload], stateCount)
 	stateParents := make([]*S, stateCount)
 	initialStates := make([]*S, stateCount)
+	stateUnhandled := make([]UnhandledHandler[S, T, Payload], stateCount)
+	stateTimeouts := make([][]stateTimeout[T], stateCount)
 
 	// Group branchDefs into slots in definition order.
 	for _, def := range b.branchDefs {
 		idx := transitionIndex(def.from, def.trigger, triggerCount)
+		step := Step[S, T]{
+			Phase:       PhaseAction,
+			State:       def.from,
+			From:        def.from,
+			To:          def.to,
+			Trigger:     def.trigger,
+			Description: def.actionDesc,
+		}
+		action := b.wrap(step, b.withResilience(step, def.resilience, def.action))
+		cond := def.cond
+		if b.recoverPanics && cond != nil {
+			cond = recoverCondition(def.from, cond)
+		}
 		br := branch[S, Payload]{
 			next:       def.to,
-			cond:       def.cond,
+			cond:       cond,
 			condDesc:   def.condDesc,
-			action:     def.action,
+			action:     action,
 			actionDesc: def.actionDesc,
 		}
 		if !slots[idx].valid {
@@ -341,6 +500,23 @@ func (b *Builder[S, T, Payload]) Build() *Spec[S, T, Payload] {
 		}
 	}
 
+	// Apply Ignore/Forbid declarations. A (state, trigger) gets exactly one kind of decision.
+	for _, def := range b.dispositions {
+		idx := transitionIndex(def.state, def.trigger, triggerCount)
+		s := &slots[idx]
+		if s.valid {
+			panic(fmt.Sprintf(
+				"trigger (%v) in state (%v) is declared both as a transition and as ignored/forbidden",
+				def.trigger, def.state,
+			))
+		}
+		if s.disposition != undeclared {
+			panic(fmt.Sprintf("trigger (%v) in state (%v) is declared ignored/forbidden more than once", def.trigger, def.state))
+		}
+		s.disposition = def.disposition
+		s.reason = def.reason
+	}
+
 	// Per-group ordering validation: unconditional branch must be last.
 	for from := uint(0); from < stateCount; from++ {
 		for trigger := uint(0); trigger < triggerCount; trigger++ {
@@ -364,8 +540,17 @@ func (b *Builder[S, T, Payload]) Build() *Spec[S, T, Payload] {
 
 	// Finalize state builders.
 	for _, sb := range b.stateBuilders {
-		if sb.isHooksSet {
-			stateHooks[sb.state] = sb.hooks
+		if sb.isHooksSet && sb.onEntry.action != nil {
+			h := sb.onEntry
+			step := Step[S, T]{Phase: PhaseEntry, State: sb.state, Description: h.desc}
+			h.action = b.wrap(step, b.withResilience(step, sb.resilience, h.action))
+			hooks[sb.state].onEntry = append(hooks[sb.state].onEntry, h)
+		}
+		if sb.isHooksSet && sb.onExit.action != nil {
+			h := sb.onExit
+			step := Step[S, T]{Phase: PhaseExit, State: sb.state, Description: h.desc}
+			h.action = b.wrap(step, b.withResilience(step, sb.resilience, h.action))
+			hooks[sb.state].onExit = append(hooks[sb.state].onExit, h)
 		}
 		if sb.isParentSet {
 			parent := sb.parent
@@ -375,6 +560,15 @@ func (b *Builder[S, T, Payload]) Build() *Spec[S, T, Payload] {
 			initial := sb.initialState
 			initialStates[sb.state] = &initial
 		}
+		if sb.isTimeoutSet {
+			stateTimeouts[sb.state] = append(stateTimeouts[sb.state], sb.timeout)
+		}
+		if sb.isUnhandledSet {
+			if stateUnhandled[sb.state] != nil {
+				panic(fmt.Sprintf("state (%v) has more than one OnUnhandled handler", sb.state))
+			}
+			stateUnhandled[sb.state] = sb.unhandled
+		}
 	}
 
 	// Ensure all initial states have the correct parent states defined.
@@ -406,12 +600,19 @@ func (b *Builder[S, T, Payload]) Build() *Spec[S, T, Payload] {
 	}
 
 	return &Spec[S, T, Payload]{
-		stateCount:    stateCount,
-		triggerCount:  triggerCount,
-		slots:         slots,
-		stateHooks:    stateHooks,
-		stateParents:  stateParents,
-		initialStates: initialStates,
+		stateCount:     stateCount,
+		triggerCount:   triggerCount,
+		slots:          slots,
+		stateHooks:     hooks,
+		stateParents:   stateParents,
+		initialStates:  initialStates,
+		unhandled:      b.unhandled,
+		stateUnhandled: stateUnhandled,
+		stateTimeouts:  stateTimeouts,
+		clock:          b.clockOrDefault(),
+		observers:      b.observers,
+		observesFire:   slices.ContainsFunc(b.observers, observesFire[S, T, Payload]),
+		recoverPanics:  b.recoverPanics,
 	}
 }
 
@@ -419,65 +620,90 @@ func transitionIndex[S, T ~uint](from S, trigger T, numTrigger uint) int {
 	return int(uint(from)*numTrigger + uint(trigger))
 }
 
-// stateBuilder holds a single state-configuration fragment (hooks, parent, or initial substate) produced by
-// fromStep's WithHooks/WithParent/WithInitial methods. Build() merges all fragments for a given state.
+// stateBuilder holds a single state-configuration fragment (a hook, parent, initial substate, unhandled handler or
+// timeout) produced by fromStep's WithHooks/OnEntry/OnExit/WithParent/WithInitial/OnUnhandled/After methods. Build() merges all
+// fragments for a given state, appending hooks in registration order.
 type stateBuilder[S, T ~uint, Payload any] struct {
 	b                 *Builder[S, T, Payload]
 	state             S
-	hooks             StateHooks[Payload]
+	onEntry           hook[Payload] // action is nil if this fragment adds no entry hook
+	onExit            hook[Payload] // action is nil if this fragment adds no exit hook
 	isHooksSet        bool
+	resilience        resilience // Timeout and Retry modifiers of the OnEntry or OnExit hook
 	parent            S
 	isParentSet       bool
 	initialState      S
 	isInitialStateSet bool
+	unhandled         UnhandledHandler[S, T, Payload]
+	isUnhandledSet    bool
+	timeout           stateTimeout[T]
+	isTimeoutSet      bool
 }
 
 // Spec represents the specification of the FSM, including its states, triggers, and transitions. It is safe to make
 // shallow copies of the Spec as it is read-only, making it thread-safe.
 type Spec[S, T ~uint, Payload any] struct {
-	stateCount    uint
-	triggerCount  uint
-	slots         []slot[S, Payload]
-	stateHooks    []StateHooks[Payload]
-	stateParents  []*S
-	initialStates []*S
-}
-
-// MermaidJSDiagram returns a state diagram in Mermaid.js syntax for the FSM Spec.
-func (spec *Spec[S, T, Payload]) MermaidJSDiagram() string {
-	diagram := "stateDiagram-v2\n"
-	for from := uint(0); from < spec.stateCount; from++ {
+	stateCount     uint
+	triggerCount   uint
+	slots          []slot[S, Payload]
+	stateHooks     []stateHooks[Payload]
+	stateParents   []*S
+	initialStates  []*S
+	unhandled      UnhandledHandler[S, T, Payload]   // Builder-level fallback; nil if unset
+	stateUnhandled []UnhandledHandler[S, T, Payload] // per-state handlers; nil entries if unset
+	stateTimeouts  [][]stateTimeout[T]               // per-state After declarations; nil entries if unset
+	clock          Clock                             // never nil
+	observers      []Observer[S, T, Payload]         // spec-level observers
+	observesFire   bool                              // some spec-level observer has OnFire
+	recoverPanics  bool                              // guards raise *PanicError panics that Fire and CanFire recover
+}
+
+// UnhandledPair is a (state, trigger) combination for which no explicit decision exists.
+type UnhandledPair[S, T ~uint] struct {
+	State   S
+	Trigger T
+}
+
+// Unhandled is an exhaustiveness report. It lists every (state, trigger) pair for which neither the state nor any of
+// its ancestors declares a transition, Ignore or Forbid. Firing such a pair calls the applicable OnUnhandled handler,
+// whatever the payload, and returns its result, or returns ErrNotFound if no handler applies; handlers do not count as
+// decisions, so their pairs are still listed. Pairs are ordered by state, then trigger.
+func (spec *Spec[S, T, Payload]) Unhandled() []UnhandledPair[S, T] {
+	var pairs []UnhandledPair[S, T]
+	for st := uint(0); st < spec.stateCount; st++ {
 		for trigger := uint(0); trigger < spec.triggerCount; trigger++ {
-			idx := transitionIndex(S(from), T(trigger), spec.triggerCount)
-			s := &spec.slots[idx]
-			if !s.valid {
-				continue
-			}
-			fromStr := fmt.Sprintf("%v", S(from))
-			triggerStr := fmt.Sprintf("%v", T(trigger))
-			branches := s.all()
-			for _, br := range branches {
-				toStr := fmt.Sprintf("%v", br.next)
-				guardDesc := ""
-				if br.condDesc != "" {
-					guardDesc = " [" + br.condDesc + "]"
-				}
-				actionDesc := ""
-				if br.actionDesc != "" {
-					actionDesc = " / " + br.actionDesc
-				}
-				diagram += fromStr + " --> " + toStr + " : " + triggerStr + guardDesc + actionDesc + "\n"
+			if !spec.isDecided(S(st), T(trigger)) {
+				pairs = append(pairs, UnhandledPair[S, T]{State: S(st), Trigger: T(trigger)})
 			}
 		}
 	}
-	return diagram
+	return pairs
+}
+
+// isDecided reports whether the state or any of its ancestors has an explicit decision for the trigger.
+func (spec *Spec[S, T, Payload]) isDecided(state S, trigger T) bool {
+	for {
+		s := &spec.slots[transitionIndex(state, trigger, spec.triggerCount)]
+		if s.valid || s.disposition != undeclared {
+			return true
+		}
+		parent := spec.stateParents[state]
+		if parent == nil {
+			return false
+		}
+		state = *parent
+	}
 }
 
 // Machine is a finite state machine (FSM) instance. It keeps track of its current state and uses the FSM specification
 // to determine valid state transitions and is the executor of defined transition actions and state hooks.
 type Machine[S, T ~uint, Payload any] struct {
-	state S
-	spec  Spec[S, T, Payload]
+	state        S
+	spec         Spec[S, T, Payload]
+	observers    []Observer[S, T, Payload]
+	observesFire bool                      // some machine-level observer has OnFire
+	firing       *FireEvent[S, T, Payload] // the event being built by fireObserved; nil otherwise
+	entered      entryTimes                // entry times of states; nil unless TrackTimeInState was called
 }
 
 // New creates a new FSM instance with the given specification and initial state.
@@ -512,11 +738,57 @@ func (m *Machine[S, T, Payload]) IsIn(state S) bool {
 // The trigger and payload together form the stimuli that attempt to stimulate the FSM to move into another state.
 //
 // If a defined transition cannot be found for the current state, it will search up the state hierarchy for
-// a valid transition until one is found. If none is found, it will return an ErrNotFound error.
+// a valid transition until one is found. If none is found, it calls the OnUnhandled handler of the deepest state in
+// the active hierarchy that has one, else the Builder-level OnUnhandled handler, and returns its result. Without any
+// handler it returns an ErrNotFound error.
 //
 // If transitions exist for (state, trigger) but no branch's condition matches, it returns ErrTransitionRejected.
 // The error message lists all tried condition descriptions from every rule-bearing level considered.
+//
+// A level that declares the trigger with Ignore or Forbid stops the search: an ignored trigger returns nil without
+// changing state, and a forbidden trigger returns an ErrTransitionForbidden error carrying the declared reason.
+//
+// If the spec was built with RecoverPanics, a panic in a guard, action or hook is returned as a *PanicError.
+//
+// Fire checks ctx before running the exit hooks, the action and the entry hooks. If ctx is done at one of these
+// boundaries it stops and returns a *CanceledError naming the phase it did not start. As with any other error, the
+// machine stays in its original state and phases that already ran are not undone.
+//
+// If ctx carries an Outbox, the messages enqueued during a Fire that returns an error or panics are discarded, so
+// that only the side effects of completed transitions are published.
 func (m *Machine[S, T, Payload]) Fire(ctx context.Context, trigger T, payload Payload) error {
+	if outbox := outboxFrom(ctx); outbox != nil {
+		return m.fireBuffered(ctx, outbox, trigger, payload)
+	}
+	return m.fireUnbuffered(ctx, trigger, payload)
+}
+
+func (m *Machine[S, T, Payload]) fireUnbuffered(ctx context.Context, trigger T, payload Payload) error {
+	if m.spec.observesFire || m.observesFire {
+		return m.fireObserved(ctx, trigger, payload)
+	}
+	return m.fireOnce(ctx, trigger, payload)
+}
+
+func (m *Machine[S, T, Payload]) fireOnce(ctx context.Context, trigger T, payload Payload) error {
+	if m.spec.recoverPanics {
+		return m.fireRecovering(ctx, trigger, payload)
+	}
+	return m.fire(ctx, trigger, payload)
+}
+
+// fireRecovering is Fire for specs built with RecoverPanics: it converts a guard panic into an error. Action and hook
+// panics are already converted by their Build-time wrappers.
+func (m *Machine[S, T, Payload]) fireRecovering(ctx context.Context, trigger T, payload Payload) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoverGuardPanic[S](r)
+		}
+	}()
+	return m.fire(ctx, trigger, payload)
+}
+
+func (m *Machine[S, T, Payload]) fire(ctx context.Context, trigger T, payload Payload) error {
 	state := m.state
 	var selected *branch[S, Payload]
 	sawSlot := false
@@ -530,7 +802,17 @@ func (m *Machine[S, T, Payload]) Fire(ctx context.Context, trigger T, payload Pa
 	var rejectedLevels []levelRejection
 
 	for {
-		if s := m.slotAt(trigger, state); s != nil && s.valid {
+		s := m.slotAt(trigger, state)
+		if s != nil {
+			switch s.disposition {
+			case ignored:
+				return nil
+			case forbidden:
+				return fmt.Errorf("trigger (%v) in state (%v) forbidden by state (%v): %s: %w",
+					trigger, m.state, state, s.reason, ErrTransitionForbidden)
+			}
+		}
+		if s != nil && s.valid {
 			sawSlot = true
 			if b := s.match(payload); b != nil {
 				selected = b
@@ -566,78 +848,106 @@ func (m *Machine[S, T, Payload]) Fire(ctx context.Context, trigger T, payload Pa
 				}
 				return fmt.Errorf("%w\n%s", ErrTransitionRejected, sb.String())
 			}
+			if handler := m.unhandledHandler(); handler != nil {
+				return handler(ctx, m.state, trigger, payload)
+			}
 			return fmt.Errorf("finding transition for trigger (%v) and current state (%v): %w", trigger, m.state, ErrNotFound)
 		}
 		state = *parent
 	}
 
-	// LCA exit/action/entry machinery (unchanged logic, reads selected.next/selected.action).
-	var lca *S
-	var lcaTargetStatesIdx *int
+	// LCA exit/action/entry machinery.
 	var sourceStatesArr [maxDepth]S
 	var targetStatesArr [maxDepth]S
-	i := m.readHierarchy(m.state, &sourceStatesArr)
-	sourceStates := sourceStatesArr[:i]
-	i = m.readHierarchy(selected.next, &targetStatesArr)
-	targetStates := targetStatesArr[:i]
-outerLoop:
-	for i := 1; i < len(sourceStates); i++ {
-		for j := 0; j < len(targetStates); j++ {
-			if sourceStates[i] == targetStates[j] {
-				lca = &sourceStates[i]
-				lcaTargetStatesIdx = &j
-				break outerLoop
-			}
-		}
-	}
+	sourceStates, exitN, targetStates, entryN := m.transitionPath(selected.next, &sourceStatesArr, &targetStatesArr)
 
-	for _, st := range sourceStates {
-		if lca != nil && st == *lca {
-			break
-		}
-		if onExit := m.spec.stateHooks[st].OnExit; onExit != nil {
-			if err := onExit(ctx, payload); err != nil {
-				return fmt.Errorf("invoking OnExit state hook for state %v: %w", st, err)
-			}
+	if err := ctx.Err(); err != nil {
+		return &CanceledError{Phase: PhaseExit, Err: err}
+	}
+	for _, st := range sourceStates[:exitN] {
+		if err := runHooks(ctx, m.spec.stateHooks[st].onExit, payload); err != nil {
+			return fmt.Errorf("invoking OnExit state hook for state %v: %w", st, err)
 		}
 	}
 
+	if err := ctx.Err(); err != nil {
+		return &CanceledError{Phase: PhaseAction, Err: err}
+	}
 	if action := selected.action; action != nil {
 		if err := action(ctx, payload); err != nil {
 			return fmt.Errorf("invoking transition action from states (%v) to (%v): %w", state, selected.next, err)
 		}
 	}
 
-	startIdx := 0
-	if lcaTargetStatesIdx != nil {
-		startIdx = *lcaTargetStatesIdx
-	} else {
-		startIdx = len(targetStates) - 1
+	if err := ctx.Err(); err != nil {
+		return &CanceledError{Phase: PhaseEntry, Err: err}
 	}
-	for i := startIdx; i >= 0; i-- {
+	for i := entryN - 1; i >= 0; i-- {
 		st := targetStates[i]
-		if lca != nil && st == *lca {
-			continue
-		}
-		if onEntry := m.spec.stateHooks[st].OnEntry; onEntry != nil {
-			if err := onEntry(ctx, payload); err != nil {
-				return fmt.Errorf("invoking OnEntry state hook for state (%v): %w", st, err)
-			}
+		if err := runHooks(ctx, m.spec.stateHooks[st].onEntry, payload); err != nil {
+			return fmt.Errorf("invoking OnEntry state hook for state (%v): %w", st, err)
 		}
 	}
 
+	next := selected.next
 	initialSubstate := m.spec.initialStates[selected.next]
 	if initialSubstate != nil {
-		if onEntry := m.spec.stateHooks[*initialSubstate].OnEntry; onEntry != nil {
-			if err := onEntry(ctx, payload); err != nil {
-				return fmt.Errorf("invoking OnEntry state hook for state (%v): %w", *initialSubstate, err)
+		if err := runHooks(ctx, m.spec.stateHooks[*initialSubstate].onEntry, payload); err != nil {
+			return fmt.Errorf("invoking OnEntry state hook for state (%v): %w", *initialSubstate, err)
+		}
+		next = *initialSubstate
+	}
+
+	from := m.state
+	m.state = next
+	var now time.Time
+	if m.entered != nil {
+		now = m.spec.clock.Now()
+	}
+	if len(m.observers) > 0 || len(m.spec.observers) > 0 {
+		m.notifyTransition(ctx, TransitionEvent[S, T, Payload]{
+			From:         from,
+			To:           next,
+			Trigger:      trigger,
+			Payload:      payload,
+			ResolvedFrom: state,
+		}, sourceStates[:exitN], targetStates[:entryN], initialSubstate, now)
+	}
+	if m.entered != nil {
+		m.markEntered(targetStates[:entryN], initialSubstate, now)
+	}
+	return nil
+}
+
+// transitionPath computes which states a transition from the current state to target exits and enters. The exited
+// states are source[:exitN], innermost first; the entered states are target[:entryN], to be entered outermost first
+// (i.e. iterated in reverse). The least common ancestor (LCA) of the two is neither exited nor entered. No allocation.
+func (m *Machine[S, T, Payload]) transitionPath(
+	target S, sourceArr, targetArr *[maxDepth]S,
+) (source []S, exitN int, targetStates []S, entryN int) {
+	source = sourceArr[:m.readHierarchy(m.state, sourceArr)]
+	targetStates = targetArr[:m.readHierarchy(target, targetArr)]
+	for i := 1; i < len(source); i++ {
+		for j := 0; j < len(targetStates); j++ {
+			if source[i] == targetStates[j] {
+				return source, i, targetStates, j
 			}
 		}
-		m.state = *initialSubstate
-		return nil
 	}
+	return source, len(source), targetStates, len(targetStates)
+}
 
-	m.state = selected.next
+// runHooks runs the hooks in order, stopping at the first error. The error names the failing hook by description
+// when it has one.
+func runHooks[Payload any](ctx context.Context, hooks []hook[Payload], payload Payload) error {
+	for i := range hooks {
+		if err := hooks[i].action(ctx, payload); err != nil {
+			if hooks[i].desc != "" {
+				return fmt.Errorf("%q: %w", hooks[i].desc, err)
+			}
+			return err
+		}
+	}
 	return nil
 }
 
@@ -645,16 +955,40 @@ outerLoop:
 // for the transition branches. The trigger and payload together form the stimuli that would attempt to stimulate the FSM.
 // It returns true if a branch matches, otherwise false.
 //
-// It will search up the state hierarchy for a valid transition until one is found or the root is reached.
-// Implemented on the shared alloc-free walk — never calls Explain.
+// It will search up the state hierarchy for a valid transition until one is found or the root is reached. A level
+// that declares the trigger with Ignore or Forbid stops the search and CanFire returns false, since no transition
+// would be made. Implemented on the shared alloc-free walk — never calls Explain.
 //
 // CanFire takes no context: conditions are pure functions of the payload (no ctx), and CanFire runs no
 // actions, so there is nothing a context could influence. This mirrors Explain, which is likewise ctx-free.
+//
+// If the spec was built with RecoverPanics, a panicking guard makes CanFire return false.
 func (m *Machine[S, T, Payload]) CanFire(trigger T, payload Payload) bool {
+	if m.spec.recoverPanics {
+		return m.canFireRecovering(trigger, payload)
+	}
+	return m.canFire(trigger, payload)
+}
+
+// canFireRecovering is CanFire for specs built with RecoverPanics.
+func (m *Machine[S, T, Payload]) canFireRecovering(trigger T, payload Payload) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			_ = recoverGuardPanic[S](r)
+			ok = false
+		}
+	}()
+	return m.canFire(trigger, payload)
+}
+
+func (m *Machine[S, T, Payload]) canFire(trigger T, payload Payload) bool {
 	state := m.state
 	for {
-		if s := m.slotAt(trigger, state); s != nil && s.valid {
-			if b := s.match(payload); b != nil {
+		if s := m.slotAt(trigger, state); s != nil {
+			if s.disposition != undeclared {
+				return false
+			}
+			if s.valid && s.match(payload) != nil {
 				return true
 			}
 			// Slot exists but no branch matched — keep bubbling (same as Fire).
@@ -674,6 +1008,7 @@ const (
 	NotMatched Outcome = iota // condition returned false
 	Matched                   // this was the winning branch
 	Skipped                   // a later branch that was never evaluated (first-match-wins)
+	Panicked                  // condition panicked; only with RecoverPanics, and Fire would return a *PanicError
 )
 
 // BranchVerdict is the evaluation result for one branch in an Explain call.
@@ -690,13 +1025,24 @@ type LevelVerdict[S ~uint] struct {
 	Branches []BranchVerdict[S]
 }
 
+// HookStep is one OnExit or OnEntry hook that a transition would run, as reported by Explain.
+type HookStep[S ~uint] struct {
+	State       S
+	Description string // the hook's description; "" if registered without one
+}
+
 // Decision is the full introspection result returned by Explain.
 type Decision[S ~uint] struct {
 	Found        bool              // did any level have a rule for (state, trigger)?
 	Matched      bool              // did a branch match anywhere?
+	Ignored      bool              // did the search stop at a level that declares the trigger with Ignore?
+	Forbidden    bool              // did the search stop at a level that declares the trigger with Forbid?
+	Reason       string            // the Forbid reason (valid iff Forbidden)
 	Target       S                 // state that would be entered (valid iff Matched)
-	ResolvedFrom S                 // level whose branch won; if none matched, the deepest level considered
+	ResolvedFrom S                 // level whose branch or declaration won; if none matched, the deepest level considered
 	Levels       []LevelVerdict[S] // deepest-first: current state, then ancestors with rules up to the resolver
+	Exits        []HookStep[S]     // OnExit hooks the transition would run, in execution order (valid iff Matched)
+	Entries      []HookStep[S]     // OnEntry hooks the transition would run, in execution order (valid iff Matched)
 }
 
 // Explain reports a multi-level decision trace for what Fire would do with the given trigger and payload.
@@ -707,6 +1053,18 @@ func (m *Machine[S, T, Payload]) Explain(trigger T, in Payload) Decision[S] {
 
 	for {
 		s := m.slotAt(trigger, state)
+		if s != nil && s.disposition != undeclared {
+			// An Ignore/Forbid declaration ends the search without selecting a branch.
+			levels = append(levels, LevelVerdict[S]{State: state})
+			return Decision[S]{
+				Found:        true,
+				Ignored:      s.disposition == ignored,
+				Forbidden:    s.disposition == forbidden,
+				Reason:       s.reason,
+				ResolvedFrom: state,
+				Levels:       levels,
+			}
+		}
 		if s == nil || !s.valid {
 			parent := m.spec.stateParents[state]
 			if parent == nil {
@@ -720,9 +1078,14 @@ func (m *Machine[S, T, Payload]) Explain(trigger T, in Payload) Decision[S] {
 		branches := s.all()
 
 		var verdicts []BranchVerdict[S]
-		matchIdx := -1
+		matchIdx, panicIdx := -1, -1
 		for i, br := range branches {
-			if br.cond == nil || br.cond(in) {
+			matched, panicked := evalCondition[S](br.cond, in)
+			if panicked {
+				panicIdx = i
+				break
+			}
+			if matched {
 				matchIdx = i
 				break
 			}
@@ -733,7 +1096,9 @@ func (m *Machine[S, T, Payload]) Explain(trigger T, in Payload) Decision[S] {
 			switch {
 			case i == matchIdx:
 				outcome = Matched
-			case matchIdx >= 0 && i > matchIdx:
+			case i == panicIdx:
+				outcome = Panicked
+			case matchIdx >= 0 && i > matchIdx, panicIdx >= 0 && i > panicIdx:
 				outcome = Skipped
 			default:
 				outcome = NotMatched
@@ -752,14 +1117,27 @@ func (m *Machine[S, T, Payload]) Explain(trigger T, in Payload) Decision[S] {
 		}
 		levels = append(levels, lv)
 
+		if panicIdx >= 0 {
+			// Fire would stop here with a *PanicError.
+			return Decision[S]{
+				Found:        true,
+				ResolvedFrom: state,
+				Levels:       levels,
+			}
+		}
+
 		if matchIdx >= 0 {
 			// This level matched — stop bubbling.
+			target := branches[matchIdx].next
+			exits, entries := m.hookSteps(target)
 			return Decision[S]{
 				Found:        true,
 				Matched:      true,
-				Target:       branches[matchIdx].next,
+				Target:       target,
 				ResolvedFrom: state,
 				Levels:       levels,
+				Exits:        exits,
+				Entries:      entries,
 			}
 		}
 
@@ -783,6 +1161,46 @@ func (m *Machine[S, T, Payload]) Explain(trigger T, in Payload) Decision[S] {
 	}
 }
 
+// hookSteps lists the OnExit and OnEntry hooks, in execution order, that Fire would run when transitioning from the
+// current state to target. It allocates; only used by Explain.
+func (m *Machine[S, T, Payload]) hookSteps(target S) (exits, entries []HookStep[S]) {
+	var sourceArr, targetArr [maxDepth]S
+	source, exitN, targetStates, entryN := m.transitionPath(target, &sourceArr, &targetArr)
+	for _, st := range source[:exitN] {
+		for _, h := range m.spec.stateHooks[st].onExit {
+			exits = append(exits, HookStep[S]{State: st, Description: h.desc})
+		}
+	}
+	entered := make([]S, 0, entryN+1)
+	for i := entryN - 1; i >= 0; i-- {
+		entered = append(entered, targetStates[i])
+	}
+	if initial := m.spec.initialStates[target]; initial != nil {
+		entered = append(entered, *initial)
+	}
+	for _, st := range entered {
+		for _, h := range m.spec.stateHooks[st].onEntry {
+			entries = append(entries, HookStep[S]{State: st, Description: h.desc})
+		}
+	}
+	return exits, entries
+}
+
+// unhandledHandler returns the OnUnhandled handler of the deepest state in the active hierarchy that has one, falling
+// back to the Builder-level handler. Only called on the not-found path.
+func (m *Machine[S, T, Payload]) unhandledHandler() UnhandledHandler[S, T, Payload] {
+	var hierarchy [maxDepth]S
+	n := m.readHierarchy(m.state, &hierarchy)
+	for _, st := range hierarchy[:n] {
+		if uint(st) < m.spec.stateCount {
+			if handler := m.spec.stateUnhandled[st]; handler != nil {
+				return handler
+			}
+		}
+	}
+	return m.spec.unhandled
+}
+
 // slotAt returns the slot for (state, trigger) with bounds checking, or nil if out of range.
 func (m *Machine[S, T, Payload]) slotAt(trigger T, state S) *slot[S, Payload] {
 	if uint(state) >= m.spec.stateCount || uint(trigger) >= m.spec.triggerCount {
@@ -2,6 +2,7 @@ package fsm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -992,3 +993,417 @@ func TestSpec_MermaidDiagram_MultipleBranches(t *testing.T) {
 	require.Contains(diagram, "locked --> unlocked : lock [cond1]")
 	require.Contains(diagram, "locked --> root : lock [cond2]")
 }
+
+// TestMachine_Fire_IgnoreAndForbid verifies that Ignore and Forbid declarations short-circuit the hierarchy walk.
+func TestMachine_Fire_IgnoreAndForbid(t *testing.T) {
+	type inp struct{}
+
+	const (
+		sA state = iota
+		sB
+		sParent
+	)
+	const tX trigger = 0
+
+	t.Run("ignored trigger is a no-op and does not bubble", func(t *testing.T) {
+		require := require.New(t)
+
+		exited := false
+		builder := NewBuilder[state, trigger, inp]()
+		builder.From(sA).WithParent(sParent).Ignore(tX).
+			WithHooks(StateHooks[inp]{OnExit: func(ctx context.Context, in inp) error {
+				exited = true
+				return nil
+			}})
+		builder.From(sParent).On(tX).To(sB)
+
+		fsm := New(builder.Build(), sA)
+		require.NoError(fsm.Fire(t.Context(), tX, inp{}))
+		require.Equal(sA, fsm.State(), "state must not change for an ignored trigger")
+		require.False(exited, "no hooks may run for an ignored trigger")
+		require.False(fsm.CanFire(tX, inp{}), "an ignored trigger makes no transition")
+	})
+
+	t.Run("forbidden trigger returns ErrTransitionForbidden with reason and does not bubble", func(t *testing.T) {
+		require := require.New(t)
+
+		builder := NewBuilder[state, trigger, inp]()
+		builder.From(sA).WithParent(sParent).Forbid(tX, "order is cancelled")
+		builder.From(sParent).On(tX).To(sB)
+
+		fsm := New(builder.Build(), sA)
+		err := fsm.Fire(t.Context(), tX, inp{})
+		require.ErrorIs(err, ErrTransitionForbidden)
+		require.ErrorContains(err, "order is cancelled")
+		require.Equal(sA, fsm.State(), "state must not change for a forbidden trigger")
+		require.False(fsm.CanFire(tX, inp{}))
+	})
+
+	t.Run("declaration on an ancestor applies after child branches reject", func(t *testing.T) {
+		require := require.New(t)
+
+		builder := NewBuilder[state, trigger, inp]()
+		builder.From(sA).WithParent(sParent).On(tX).To(sB).When("never", func(inp) bool { return false })
+		builder.From(sParent).Ignore(tX)
+
+		fsm := New(builder.Build(), sA)
+		require.NoError(fsm.Fire(t.Context(), tX, inp{}))
+		require.Equal(sA, fsm.State())
+	})
+
+	t.Run("Build panics when a trigger is both a transition and a declaration", func(t *testing.T) {
+		builder := NewBuilder[state, trigger, inp]()
+		builder.From(sA).On(tX).To(sB)
+		builder.From(sA).Ignore(tX)
+
+		require.Panics(t, func() { builder.Build() })
+	})
+
+	t.Run("Build panics when a trigger is declared more than once", func(t *testing.T) {
+		builder := NewBuilder[state, trigger, inp]()
+		builder.From(sA).Ignore(tX).Forbid(tX, "no")
+
+		require.Panics(t, func() { builder.Build() })
+	})
+}
+
+// TestMachine_Explain_IgnoreAndForbid verifies that Explain reports Ignore and Forbid declarations.
+func TestMachine_Explain_IgnoreAndForbid(t *testing.T) {
+	type inp struct{}
+
+	const (
+		sA state = iota
+		sB
+		sParent
+	)
+	const tX trigger = 0
+
+	t.Run("ignored", func(t *testing.T) {
+		require := require.New(t)
+
+		builder := NewBuilder[state, trigger, inp]()
+		builder.From(sA).WithParent(sParent)
+		builder.From(sParent).Ignore(tX)
+
+		d := New(builder.Build(), sA).Explain(tX, inp{})
+
+		require.True(d.Found)
+		require.False(d.Matched)
+		require.True(d.Ignored)
+		require.False(d.Forbidden)
+		require.Equal(sParent, d.ResolvedFrom)
+		require.Len(d.Levels, 1)
+		require.Equal(sParent, d.Levels[0].State)
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		require := require.New(t)
+
+		builder := NewBuilder[state, trigger, inp]()
+		builder.From(sA).Forbid(tX, "not allowed")
+		builder.From(sB).On(tX).To(sA)
+
+		d := New(builder.Build(), sA).Explain(tX, inp{})
+
+		require.True(d.Found)
+		require.False(d.Matched)
+		require.True(d.Forbidden)
+		require.Equal("not allowed", d.Reason)
+		require.Equal(sA, d.ResolvedFrom)
+	})
+}
+
+// TestSpec_Unhandled verifies the exhaustiveness report, including decisions inherited from ancestors.
+func TestSpec_Unhandled(t *testing.T) {
+	require := require.New(t)
+
+	/* ---------------------------------- Given --------------------------------- */
+	builder := NewBuilder[state, trigger, payload]()
+	builder.From(locked).On(unlock).To(unlocked)
+	builder.From(locked).Ignore(lock)
+	builder.From(unlocked).Forbid(unlock, "already unlocked")
+	builder.From(child).WithParent(root)
+	builder.From(root).On(lock).To(locked)
+
+	/* ---------------------------------- When ---------------------------------- */
+	got := builder.Build().Unhandled()
+
+	/* ---------------------------------- Then ---------------------------------- */
+	require.Equal([]UnhandledPair[state, trigger]{
+		{State: unlocked, Trigger: lock},
+		{State: root, Trigger: unlock},
+		{State: child, Trigger: unlock},
+	}, got)
+}
+
+// TestMachine_Fire_OnUnhandled verifies that unhandled triggers are routed to the nearest OnUnhandled handler.
+func TestMachine_Fire_OnUnhandled(t *testing.T) {
+	type inp struct{ id int }
+
+	const (
+		sA state = iota
+		sB
+		sParent
+	)
+	const (
+		tX trigger = iota
+		tY
+	)
+
+	errDomain := errors.New("domain error")
+
+	t.Run("builder-level handler receives the stimuli and its result is returned", func(t *testing.T) {
+		require := require.New(t)
+
+		var gotState state
+		var gotTrigger trigger
+		var gotPayload inp
+		builder := NewBuilder[state, trigger, inp]()
+		builder.From(sA).On(tX).To(sB)
+		builder.OnUnhandled(func(ctx context.Context, s state, tr trigger, in inp) error {
+			gotState, gotTrigger, gotPayload = s, tr, in
+			return errDomain
+		})
+
+		fsm := New(builder.Build(), sA)
+		err := fsm.Fire(t.Context(), tY, inp{id: 7})
+		require.ErrorIs(err, errDomain)
+		require.NotErrorIs(err, ErrNotFound)
+		require.Equal(sA, gotState)
+		require.Equal(tY, gotTrigger)
+		require.Equal(inp{id: 7}, gotPayload)
+		require.Equal(sA, fsm.State())
+	})
+
+	t.Run("handler can swallow the event", func(t *testing.T) {
+		require := require.New(t)
+
+		builder := NewBuilder[state, trigger, inp]()
+		builder.From(sA).On(tX).To(sB)
+		builder.OnUnhandled(func(context.Context, state, trigger, inp) error { return nil })
+
+		require.NoError(New(builder.Build(), sA).Fire(t.Context(), tY, inp{}))
+	})
+
+	t.Run("nearest composite-state handler wins over the builder-level handler", func(t *testing.T) {
+		require := require.New(t)
+
+		var called string
+		builder := NewBuilder[state, trigger, inp]()
+		builder.From(sA).WithParent(sParent).On(tX).To(sB)
+		builder.From(sParent).OnUnhandled(func(context.Context, state, trigger, inp) error {
+			called = "parent"
+			return nil
+		})
+		builder.OnUnhandled(func(context.Context, state, trigger, inp) error {
+			called = "builder"
+			return nil
+		})
+		spec := builder.Build()
+
+		require.NoError(New(spec, sA).Fire(t.Context(), tY, inp{}))
+		require.Equal("parent", called)
+
+		require.NoError(New(spec, sB).Fire(t.Context(), tY, inp{}))
+		require.Equal("builder", called, "sB is outside sParent so the builder-level handler applies")
+	})
+
+	t.Run("handler is not called for rejected or forbidden triggers", func(t *testing.T) {
+		require := require.New(t)
+
+		called := false
+		builder := NewBuilder[state, trigger, inp]()
+		builder.From(sA).On(tX).To(sB).When("never", func(inp) bool { return false })
+		builder.From(sA).Forbid(tY, "no")
+		builder.OnUnhandled(func(context.Context, state, trigger, inp) error {
+			called = true
+			return nil
+		})
+
+		fsm := New(builder.Build(), sA)
+		require.ErrorIs(fsm.Fire(t.Context(), tX, inp{}), ErrTransitionRejected)
+		require.ErrorIs(fsm.Fire(t.Context(), tY, inp{}), ErrTransitionForbidden)
+		require.False(called)
+	})
+
+	t.Run("Build panics when a state has more than one handler", func(t *testing.T) {
+		builder := NewBuilder[state, trigger, inp]()
+		noop := func(context.Context, state, trigger, inp) error { return nil }
+		builder.From(sA).OnUnhandled(noop).OnUnhandled(noop)
+
+		require.Panics(t, func() { builder.Build() })
+	})
+}
+
+// TestFromStep_OnEntryOnExit verifies that hooks are additive and run in registration order.
+func TestFromStep_OnEntryOnExit(t *testing.T) {
+	require := require.New(t)
+
+	/* ---------------------------------- Given --------------------------------- */
+	var calls []string
+	record := func(name string) func(context.Context, payload) error {
+		return func(context.Context, payload) error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+	builder := NewBuilder[state, trigger, payload]()
+	builder.From(locked).On(unlock).To(unlocked)
+	builder.From(locked).
+		OnExit("audit exit", record("locked exit 1")).
+		WithHooks(StateHooks[payload]{OnExit: record("locked exit 2")})
+	builder.From(locked).OnExit("metrics", record("locked exit 3"))
+	builder.From(unlocked).
+		WithHooks(StateHooks[payload]{OnEntry: record("unlocked entry 1")}).
+		OnEntry("notify", record("unlocked entry 2"))
+	spec := builder.Build()
+
+	/* ---------------------------------- When ---------------------------------- */
+	err := New(spec, locked).Fire(t.Context(), unlock, payload{})
+
+	/* ---------------------------------- Then ---------------------------------- */
+	require.NoError(err)
+	require.Equal([]string{
+		"locked exit 1", "locked exit 2", "locked exit 3",
+		"unlocked entry 1", "unlocked entry 2",
+	}, calls)
+}
+
+// TestMachine_Fire_HookErrorStopsLaterHooks verifies that a failing hook stops the remaining hooks and is named in
+// the error.
+func TestMachine_Fire_HookErrorStopsLaterHooks(t *testing.T) {
+	require := require.New(t)
+
+	/* ---------------------------------- Given --------------------------------- */
+	errHook := errors.New("boom")
+	secondCalled := false
+	builder := NewBuilder[state, trigger, payload]()
+	builder.From(locked).On(unlock).To(unlocked)
+	builder.From(unlocked).
+		OnEntry("first", func(context.Context, payload) error { return errHook }).
+		OnEntry("second", func(context.Context, payload) error {
+			secondCalled = true
+			return nil
+		})
+
+	/* ---------------------------------- When ---------------------------------- */
+	err := New(builder.Build(), locked).Fire(t.Context(), unlock, payload{})
+
+	/* ---------------------------------- Then ---------------------------------- */
+	require.ErrorIs(err, errHook)
+	require.ErrorContains(err, `"first"`)
+	require.False(secondCalled)
+}
+
+// TestSpec_MermaidDiagram_HookDescriptions verifies that described hooks are rendered as notes.
+func TestSpec_MermaidDiagram_HookDescriptions(t *testing.T) {
+	require := require.New(t)
+
+	noop := func(context.Context, payload) error { return nil }
+	builder := NewBuilder[state, trigger, payload]()
+	builder.From(locked).On(unlock).To(unlocked)
+	builder.From(unlocked).OnEntry("turn on light", noop).OnEntry("start timer", noop).OnExit("stop timer", noop)
+	builder.From(locked).WithHooks(StateHooks[payload]{OnEntry: noop}) // undescribed hooks are not rendered
+
+	diagram := builder.Build().MermaidJSDiagram()
+
+	require.Contains(diagram, "note right of unlocked\n\tentry / turn on light\n\tentry / start timer\n\texit / stop timer\nend note\n")
+	require.NotContains(diagram, "note right of locked\n")
+}
+
+// TestMachine_Explain_HookSteps verifies that Explain lists the hooks a transition would run, in execution order.
+func TestMachine_Explain_HookSteps(t *testing.T) {
+	require := require.New(t)
+
+	/* ---------------------------------- Given --------------------------------- */
+	noop := func(context.Context, payload) error { return nil }
+	builder := NewBuilder[state, trigger, payload]()
+	builder.From(child).WithParent(root).OnExit("leave child", noop)
+	builder.From(grandchild).WithParent(child).OnExit("leave grandchild", noop)
+	builder.From(unlocked).WithParent(root).WithInitial(locked).OnEntry("enter unlocked", noop)
+	builder.From(locked).WithParent(unlocked).OnEntry("enter locked", noop)
+	builder.From(root).OnExit("leave root", noop) // root is the LCA, so its hooks never run
+	builder.From(grandchild).On(unlock).To(unlocked)
+
+	/* ---------------------------------- When ---------------------------------- */
+	d := New(builder.Build(), grandchild).Explain(unlock, payload{})
+
+	/* ---------------------------------- Then ---------------------------------- */
+	require.True(d.Matched)
+	require.Equal([]HookStep[state]{
+		{State: grandchild, Description: "leave grandchild"},
+		{State: child, Description: "leave child"},
+	}, d.Exits)
+	require.Equal([]HookStep[state]{
+		{State: unlocked, Description: "enter unlocked"},
+		{State: locked, Description: "enter locked"},
+	}, d.Entries)
+}
+
+// TestMachine_Fire_ContextCancellationBetweenPhases verifies that Fire stops at the next phase boundary once its
+// context is done, reporting the phase that did not start and leaving the state unchanged.
+func TestMachine_Fire_ContextCancellationBetweenPhases(t *testing.T) {
+	tests := []struct {
+		name      string
+		cancelIn  string // the step that cancels the context
+		wantPhase Phase
+		wantCalls []string
+	}{
+		{
+			name:      "cancelled before Fire",
+			cancelIn:  "",
+			wantPhase: PhaseExit,
+			wantCalls: nil,
+		},
+		{
+			name:      "cancelled by an exit hook",
+			cancelIn:  "exit",
+			wantPhase: PhaseAction,
+			wantCalls: []string{"exit"},
+		},
+		{
+			name:      "cancelled by the action",
+			cancelIn:  "action",
+			wantPhase: PhaseEntry,
+			wantCalls: []string{"exit", "action"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			/* ---------------------------------- Given --------------------------------- */
+			ctx, cancel := context.WithCancel(t.Context())
+			if tt.cancelIn == "" {
+				cancel()
+			}
+			var calls []string
+			step := func(name string) func(context.Context, payload) error {
+				return func(context.Context, payload) error {
+					calls = append(calls, name)
+					if name == tt.cancelIn {
+						cancel() // simulates a request cancelled while the step ignores ctx
+					}
+					return nil
+				}
+			}
+			builder := NewBuilder[state, trigger, payload]()
+			builder.From(locked).On(unlock).To(unlocked).Do("action", step("action"))
+			builder.From(locked).OnExit("exit", step("exit"))
+			builder.From(unlocked).OnEntry("entry", step("entry"))
+			machine := New(builder.Build(), locked)
+
+			/* ---------------------------------- When ---------------------------------- */
+			err := machine.Fire(ctx, unlock, payload{})
+
+			/* ---------------------------------- Then ---------------------------------- */
+			var canceledErr *CanceledError
+			require.ErrorAs(err, &canceledErr)
+			require.Equal(tt.wantPhase, canceledErr.Phase)
+			require.ErrorIs(err, context.Canceled)
+			require.Equal(tt.wantCalls, calls)
+			require.Equal(locked, machine.State(), "state must not change when cancelled")
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Package fsmotel traces fsm machines with OpenTelemetry. Each Fire becomes a span carrying the from, to and trigger,
+// the hierarchy level that resolved it and its outcome, with a child span per exit hook, action and entry hook, and a
+// span event per guard that rejected the trigger. Only machines fired through Tracer.Fire get the step spans as
+// children: for machines fired directly the Fire span is recorded after the fact, so the step spans are its siblings.
+// It lives in its own module so that the fsm core stays free of dependencies.
+package fsmotel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tobbstr/fsm"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope name of the tracer.
+const ScopeName = "github.com/tobbstr/fsm/fsmotel"
+
+// Attribute keys set on spans.
+const (
+	FromKey         = attribute.Key("fsm.from")
+	ToKey           = attribute.Key("fsm.to")
+	TriggerKey      = attribute.Key("fsm.trigger")
+	ResolvedFromKey = attribute.Key("fsm.resolved_from")
+	OutcomeKey      = attribute.Key("fsm.outcome")
+	StateKey        = attribute.Key("fsm.state")
+	DescriptionKey  = attribute.Key("fsm.description")
+	ConditionKey    = attribute.Key("fsm.condition")
+	TargetKey       = attribute.Key("fsm.target")
+)
+
+// Tracer creates spans for machines created from a spec. Register it on the builder with Instrument, and fire through
+// Tracer.Fire so that hooks and the action are traced as children of the Fire span.
+type Tracer[S, T ~uint, Payload any] struct {
+	tracer trace.Tracer
+}
+
+// NewTracer creates a Tracer using provider, e.g. otel.GetTracerProvider().
+func NewTracer[S, T ~uint, Payload any](provider trace.TracerProvider) *Tracer[S, T, Payload] {
+	return &Tracer[S, T, Payload]{tracer: provider.Tracer(ScopeName)}
+}
+
+// Instrument registers the tracer's middleware and observer on builder.
+func (t *Tracer[S, T, Payload]) Instrument(builder *fsm.Builder[S, T, Payload]) *fsm.Builder[S, T, Payload] {
+	return builder.Use(t.Middleware()).Observe(t.Observer())
+}
+
+// fireSpanKey marks a context whose span was started by Tracer.Fire.
+type fireSpanKey struct{}
+
+// Fire fires the trigger on the machine inside a "fsm.Fire" span. If no branch's guard matched, it adds a
+// "fsm.guard_rejected" event per rejecting guard, found with Machine.Explain, so guards must be free of side effects.
+func (t *Tracer[S, T, Payload]) Fire(ctx context.Context, machine *fsm.Machine[S, T, Payload], trigger T, payload Payload) error {
+	ctx, span := t.tracer.Start(ctx, "fsm.Fire", trace.WithAttributes(
+		FromKey.String(fmt.Sprint(machine.State())),
+		TriggerKey.String(fmt.Sprint(trigger)),
+	))
+	defer span.End()
+	err := machine.Fire(context.WithValue(ctx, fireSpanKey{}, span), trigger, payload)
+	if errors.Is(err, fsm.ErrTransitionRejected) {
+		for _, level := range machine.Explain(trigger, payload).Levels {
+			for _, b := range level.Branches {
+				if b.Outcome == fsm.NotMatched {
+					span.AddEvent("fsm.guard_rejected", trace.WithAttributes(
+						StateKey.String(fmt.Sprint(level.State)),
+						ConditionKey.String(b.Condition),
+						TargetKey.String(fmt.Sprint(b.Target)),
+					))
+				}
+			}
+		}
+	}
+	return err
+}
+
+// Observer returns the observer that completes the Fire span with the outcome. For machines fired directly rather than
+// through Tracer.Fire — e.g. by a Scheduler or Repository — it records a "fsm.Fire" span after the fact, with the
+// measured start and end times. The middleware's hook and action spans of such a Fire are not nested under it, but are
+// its siblings, children of the span in the context passed to Fire.
+func (t *Tracer[S, T, Payload]) Observer() fsm.Observer[S, T, Payload] {
+	return fsm.Observer[S, T, Payload]{OnFire: t.onFire}
+}
+
+func (t *Tracer[S, T, Payload]) onFire(ctx context.Context, event fsm.FireEvent[S, T, Payload]) {
+	span, ok := ctx.Value(fireSpanKey{}).(trace.Span)
+	if !ok {
+		_, span = t.tracer.Start(ctx, "fsm.Fire", trace.WithTimestamp(event.At), trace.WithAttributes(
+			FromKey.String(fmt.Sprint(event.From)),
+			TriggerKey.String(fmt.Sprint(event.Trigger)),
+		))
+		defer span.End(trace.WithTimestamp(event.At.Add(event.Duration)))
+	}
+	span.SetAttributes(ToKey.String(fmt.Sprint(event.To)), OutcomeKey.String(event.Result().String()))
+	if event.Transitioned {
+		span.SetAttributes(ResolvedFromKey.String(fmt.Sprint(event.ResolvedFrom)))
+	}
+	if event.Err != nil {
+		span.RecordError(event.Err)
+		span.SetStatus(codes.Error, event.Result().String())
+	}
+}
+
+// Middleware returns the middleware that wraps each exit hook, action and entry hook in a child span named after its
+// phase, e.g. "fsm.exit". The step runs without the Tracer.Fire span in its context, so that a machine it fires
+// directly records a Fire span of its own rather than completing the outer one.
+func (t *Tracer[S, T, Payload]) Middleware() fsm.Middleware[S, T, Payload] {
+	return func(step fsm.Step[S, T], next fsm.Action[Payload]) fsm.Action[Payload] {
+		name := "fsm." + step.Phase.String()
+		attrs := []attribute.KeyValue{StateKey.String(fmt.Sprint(step.State)), DescriptionKey.String(step.Description)}
+		if step.Phase == fsm.PhaseAction {
+			// Hooks belong to a state rather than a transition, so only actions know the transition.
+			attrs = append(attrs,
+				FromKey.String(fmt.Sprint(step.From)),
+				ToKey.String(fmt.Sprint(step.To)),
+				TriggerKey.String(fmt.Sprint(step.Trigger)),
+			)
+		}
+		return func(ctx context.Context, payload Payload) error {
+			ctx, span := t.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+			defer span.End()
+			if ctx.Value(fireSpanKey{}) != nil {
+				ctx = context.WithValue(ctx, fireSpanKey{}, nil)
+			}
+			err := next(ctx, payload)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package fsmotel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tobbstr/fsm"
+	"github.com/tobbstr/fsm/fsmotel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type orderState uint
+
+const (
+	created orderState = iota
+	paid
+	shipped
+)
+
+func (s orderState) String() string {
+	return [...]string{"Created", "Paid", "Shipped"}[s]
+}
+
+type orderTrigger uint
+
+const (
+	pay orderTrigger = iota
+	ship
+)
+
+func (t orderTrigger) String() string {
+	return [...]string{"Pay", "Ship"}[t]
+}
+
+type orderPayload struct {
+	inStock bool
+	failPay bool
+}
+
+func newTracedSpec(tracer *fsmotel.Tracer[orderState, orderTrigger, orderPayload]) *fsm.Spec[orderState, orderTrigger, orderPayload] {
+	b := tracer.Instrument(fsm.NewBuilder[orderState, orderTrigger, orderPayload]())
+	noop := func(context.Context, orderPayload) error { return nil }
+	b.From(created).OnExit("release reservation", noop)
+	b.From(paid).OnEntry("send receipt", noop)
+	b.From(created).On(pay).To(paid).Do("charge card", func(_ context.Context, p orderPayload) error {
+		if p.failPay {
+			return errors.New("card declined")
+		}
+		return nil
+	})
+	b.From(paid).On(ship).To(shipped).When("in stock", func(p orderPayload) bool { return p.inStock })
+	return b.Build()
+}
+
+func newTracer(t *testing.T) (*fsmotel.Tracer[orderState, orderTrigger, orderPayload], *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return fsmotel.NewTracer[orderState, orderTrigger, orderPayload](provider), exporter
+}
+
+// spanNames returns the names of the spans in end order.
+func spanNames(spans tracetest.SpanStubs) []string {
+	var names []string
+	for _, s := range spans {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func attr(s tracetest.SpanStub, key attribute.Key) string {
+	for _, kv := range s.Attributes {
+		if kv.Key == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestTracer(t *testing.T) {
+	t.Run("traces a transition with child spans for hooks and the action", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		tracer, exporter := newTracer(t)
+		machine := fsm.New(newTracedSpec(tracer), created)
+
+		/* ---------------------------------- When ---------------------------------- */
+		require.NoError(tracer.Fire(t.Context(), machine, pay, orderPayload{}))
+
+		/* ---------------------------------- Then ---------------------------------- */
+		spans := exporter.GetSpans()
+		require.Equal([]string{"fsm.exit", "fsm.action", "fsm.entry", "fsm.Fire"}, spanNames(spans))
+		fire := spans[3]
+		for _, child := range spans[:3] {
+			require.Equal(fire.SpanContext.SpanID(), child.Parent.SpanID())
+		}
+		require.Equal("Created", attr(fire, fsmotel.FromKey))
+		require.Equal("Paid", attr(fire, fsmotel.ToKey))
+		require.Equal("Pay", attr(fire, fsmotel.TriggerKey))
+		require.Equal("Created", attr(fire, fsmotel.ResolvedFromKey))
+		require.Equal("transitioned", attr(fire, fsmotel.OutcomeKey))
+		require.Equal("release reservation", attr(spans[0], fsmotel.DescriptionKey))
+		require.Equal("charge card", attr(spans[1], fsmotel.DescriptionKey))
+		require.Equal("Paid", attr(spans[1], fsmotel.ToKey))
+		require.Equal("Paid", attr(spans[2], fsmotel.StateKey))
+	})
+
+	t.Run("marks failed actions and fires as errors", func(t *testing.T) {
+		require := require.New(t)
+
+		tracer, exporter := newTracer(t)
+		machine := fsm.New(newTracedSpec(tracer), created)
+
+		require.Error(tracer.Fire(t.Context(), machine, pay, orderPayload{failPay: true}))
+
+		spans := exporter.GetSpans()
+		require.Equal([]string{"fsm.exit", "fsm.action", "fsm.Fire"}, spanNames(spans))
+		require.Equal(codes.Error, spans[1].Status.Code)
+		require.Equal(codes.Error, spans[2].Status.Code)
+		require.Equal("failed", attr(spans[2], fsmotel.OutcomeKey))
+	})
+
+	t.Run("adds an event per rejecting guard", func(t *testing.T) {
+		require := require.New(t)
+
+		tracer, exporter := newTracer(t)
+		machine := fsm.New(newTracedSpec(tracer), paid)
+
+		require.ErrorIs(tracer.Fire(t.Context(), machine, ship, orderPayload{}), fsm.ErrTransitionRejected)
+
+		spans := exporter.GetSpans()
+		require.Equal([]string{"fsm.Fire"}, spanNames(spans))
+		require.Equal("rejected", attr(spans[0], fsmotel.OutcomeKey))
+		var guards []string
+		for _, e := range spans[0].Events {
+			if e.Name == "fsm.guard_rejected" {
+				for _, kv := range e.Attributes {
+					if kv.Key == fsmotel.ConditionKey {
+						guards = append(guards, kv.Value.AsString())
+					}
+				}
+			}
+		}
+		require.Equal([]string{"in stock"}, guards)
+	})
+
+	t.Run("records machines fired directly after the fact", func(t *testing.T) {
+		require := require.New(t)
+
+		tracer, exporter := newTracer(t)
+		machine := fsm.New(newTracedSpec(tracer), created)
+
+		require.NoError(machine.Fire(t.Context(), pay, orderPayload{}))
+
+		spans := exporter.GetSpans()
+		require.Equal([]string{"fsm.exit", "fsm.action", "fsm.entry", "fsm.Fire"}, spanNames(spans))
+		fire := spans[3]
+		require.Equal("Paid", attr(fire, fsmotel.ToKey))
+		require.False(fire.Parent.IsValid())
+		for _, step := range spans[:3] {
+			require.Equal(fire.Parent, step.Parent, "step spans are siblings of the Fire span")
+		}
+		require.False(fire.StartTime.After(spans[0].StartTime), "the span starts when Fire was called")
+	})
+	t.Run("records machines fired directly by an action with their own spans", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		tracer, exporter := newTracer(t)
+		inner := fsm.New(newTracedSpec(tracer), paid)
+		b := tracer.Instrument(fsm.NewBuilder[orderState, orderTrigger, orderPayload]())
+		b.From(created).On(pay).To(paid).Do("ship order", func(ctx context.Context, p orderPayload) error {
+			_ = inner.Fire(ctx, ship, p) // rejected: out of stock
+			return nil
+		})
+		outer := fsm.New(b.Build(), created)
+
+		/* ---------------------------------- When ---------------------------------- */
+		require.NoError(tracer.Fire(t.Context(), outer, pay, orderPayload{}))
+
+		/* ---------------------------------- Then ---------------------------------- */
+		spans := exporter.GetSpans()
+		require.Equal([]string{"fsm.Fire", "fsm.action", "fsm.Fire"}, spanNames(spans))
+		innerFire, outerFire := spans[0], spans[2]
+		require.Equal("rejected", attr(innerFire, fsmotel.OutcomeKey))
+		require.Equal(spans[1].SpanContext.SpanID(), innerFire.Parent.SpanID())
+		require.Equal("transitioned", attr(outerFire, fsmotel.OutcomeKey))
+		require.Equal(codes.Unset, outerFire.Status.Code)
+		require.Empty(outerFire.Events)
+	})
+}
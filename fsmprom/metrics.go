@@ -0,0 +1,163 @@
+// Package fsmprom collects Prometheus metrics from fsm machines: transitions by (from, to, trigger), rejections by
+// (state, trigger, result), action and hook errors, and Fire and step latencies. Labels use the String() names of
+// states and triggers, capped per label by a cardinality guard. It lives in its own module so that the fsm core stays
+// free of dependencies.
+package fsmprom
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tobbstr/fsm"
+)
+
+// OverflowLabel replaces label values beyond the cardinality limit.
+const OverflowLabel = "other"
+
+// Options configures Metrics. The zero value is usable.
+type Options struct {
+	// Namespace prefixes metric names; the default is "fsm".
+	Namespace string
+	// ConstLabels are added to every metric, e.g. {"spec": "order"} to tell specs apart.
+	ConstLabels prometheus.Labels
+	// MaxLabelValues caps the distinct values of each label; further values are reported as OverflowLabel. The
+	// default is 100.
+	MaxLabelValues int
+	// Buckets are the latency histogram buckets, in seconds; the default is prometheus.DefBuckets.
+	Buckets []float64
+}
+
+// Metrics is a prometheus.Collector fed by machines created from a spec. Register it on the builder with Instrument
+// and on a registry with prometheus.Registerer.Register.
+type Metrics[S, T ~uint, Payload any] struct {
+	transitions  *prometheus.CounterVec
+	rejections   *prometheus.CounterVec
+	stepErrors   *prometheus.CounterVec
+	fireDuration *prometheus.HistogramVec
+	stepDuration *prometheus.HistogramVec
+	guard        *cardinalityGuard
+}
+
+var _ prometheus.Collector = (*Metrics[uint, uint, struct{}])(nil)
+
+// New creates Metrics configured by opts.
+func New[S, T ~uint, Payload any](opts Options) *Metrics[S, T, Payload] {
+	if opts.Namespace == "" {
+		opts.Namespace = "fsm"
+	}
+	if opts.MaxLabelValues <= 0 {
+		opts.MaxLabelValues = 100
+	}
+	if opts.Buckets == nil {
+		opts.Buckets = prometheus.DefBuckets
+	}
+	counter := func(name, help string, labels ...string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: opts.Namespace, Name: name, Help: help, ConstLabels: opts.ConstLabels,
+		}, labels)
+	}
+	histogram := func(name, help string, labels ...string) *prometheus.HistogramVec {
+		return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: opts.Namespace, Name: name, Help: help, ConstLabels: opts.ConstLabels, Buckets: opts.Buckets,
+		}, labels)
+	}
+	return &Metrics[S, T, Payload]{
+		transitions: counter("transitions_total", "Completed transitions.", "from", "to", "trigger"),
+		rejections: counter("rejections_total",
+			"Fire calls that did not transition because the trigger was rejected, forbidden or not found.",
+			"state", "trigger", "result"),
+		stepErrors: counter("step_errors_total", "Failed actions and hooks.", "phase", "state"),
+		fireDuration: histogram("fire_duration_seconds", "Duration of Fire calls, on the spec's clock.",
+			"trigger", "result"),
+		stepDuration: histogram("step_duration_seconds", "Duration of actions and hooks.", "phase", "state"),
+		guard:        &cardinalityGuard{max: opts.MaxLabelValues, seen: make(map[string]map[string]struct{})},
+	}
+}
+
+// Instrument registers the metrics' middleware and observer on builder.
+func (m *Metrics[S, T, Payload]) Instrument(builder *fsm.Builder[S, T, Payload]) *fsm.Builder[S, T, Payload] {
+	return builder.Use(m.Middleware()).Observe(m.Observer())
+}
+
+// Observer returns the observer counting Fire calls by result. It can also be registered on individual machines.
+func (m *Metrics[S, T, Payload]) Observer() fsm.Observer[S, T, Payload] {
+	return fsm.Observer[S, T, Payload]{OnFire: m.onFire}
+}
+
+func (m *Metrics[S, T, Payload]) onFire(_ context.Context, event fsm.FireEvent[S, T, Payload]) {
+	result := event.Result()
+	trigger := m.guard.label("trigger", event.Trigger)
+	m.fireDuration.WithLabelValues(trigger, result.String()).Observe(event.Duration.Seconds())
+	switch result {
+	case fsm.Transitioned:
+		m.transitions.WithLabelValues(m.guard.label("from", event.From), m.guard.label("to", event.To), trigger).Inc()
+	case fsm.Rejected, fsm.Forbidden, fsm.NotFound:
+		m.rejections.WithLabelValues(m.guard.label("state", event.From), trigger, result.String()).Inc()
+	}
+}
+
+// Middleware returns the middleware timing each action and hook and counting its errors.
+func (m *Metrics[S, T, Payload]) Middleware() fsm.Middleware[S, T, Payload] {
+	return func(step fsm.Step[S, T], next fsm.Action[Payload]) fsm.Action[Payload] {
+		phase, state := step.Phase.String(), m.guard.label("state", step.State)
+		duration := m.stepDuration.WithLabelValues(phase, state)
+		errors := m.stepErrors.WithLabelValues(phase, state)
+		return func(ctx context.Context, payload Payload) error {
+			start := time.Now()
+			err := next(ctx, payload)
+			duration.Observe(time.Since(start).Seconds())
+			if err != nil {
+				errors.Inc()
+			}
+			return err
+		}
+	}
+}
+
+// Describe sends the descriptors of the metrics.
+func (m *Metrics[S, T, Payload]) Describe(ch chan<- *prometheus.Desc) {
+	m.transitions.Describe(ch)
+	m.rejections.Describe(ch)
+	m.stepErrors.Describe(ch)
+	m.fireDuration.Describe(ch)
+	m.stepDuration.Describe(ch)
+}
+
+// Collect sends the current values of the metrics.
+func (m *Metrics[S, T, Payload]) Collect(ch chan<- prometheus.Metric) {
+	m.transitions.Collect(ch)
+	m.rejections.Collect(ch)
+	m.stepErrors.Collect(ch)
+	m.fireDuration.Collect(ch)
+	m.stepDuration.Collect(ch)
+}
+
+// cardinalityGuard caps the distinct values reported for each label name.
+type cardinalityGuard struct {
+	mu   sync.Mutex
+	max  int
+	seen map[string]map[string]struct{} // label name -> values admitted so far
+}
+
+// label returns the String() name of v, or OverflowLabel if the label already has max other values.
+func (g *cardinalityGuard) label(name string, v any) string {
+	value := fmt.Sprint(v)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	values := g.seen[name]
+	if values == nil {
+		values = make(map[string]struct{})
+		g.seen[name] = values
+	}
+	if _, ok := values[value]; ok {
+		return value
+	}
+	if len(values) >= g.max {
+		return OverflowLabel
+	}
+	values[value] = struct{}{}
+	return value
+}
@@ -0,0 +1,134 @@
+package fsmprom_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/require"
+	"github.com/tobbstr/fsm"
+	"github.com/tobbstr/fsm/fsmprom"
+	"github.com/tobbstr/fsm/fsmtest"
+)
+
+type orderState uint
+
+const (
+	created orderState = iota
+	paid
+	shipped
+)
+
+func (s orderState) String() string {
+	return [...]string{"Created", "Paid", "Shipped"}[s]
+}
+
+type orderTrigger uint
+
+const (
+	pay orderTrigger = iota
+	ship
+	refund
+)
+
+func (t orderTrigger) String() string {
+	return [...]string{"Pay", "Ship", "Refund"}[t]
+}
+
+type orderPayload struct {
+	inStock bool
+	failPay bool
+}
+
+func newMeteredSpec(metrics *fsmprom.Metrics[orderState, orderTrigger, orderPayload]) *fsm.Spec[orderState, orderTrigger, orderPayload] {
+	b := metrics.Instrument(fsm.NewBuilder[orderState, orderTrigger, orderPayload]().WithClock(fsmtest.NewClock(time.Unix(0, 0))))
+	b.From(paid).OnEntry("send receipt", func(context.Context, orderPayload) error { return nil })
+	b.From(created).On(pay).To(paid).Do("charge card", func(_ context.Context, p orderPayload) error {
+		if p.failPay {
+			return errors.New("card declined")
+		}
+		return nil
+	})
+	b.From(paid).On(ship).To(shipped).When("in stock", func(p orderPayload) bool { return p.inStock })
+	b.From(shipped).Forbid(refund, "already shipped")
+	return b.Build()
+}
+
+// scrape returns the metrics of registry in the Prometheus text format, as served over HTTP.
+func scrape(t *testing.T, registry *prometheus.Registry) string {
+	t.Helper()
+	server := httptest.NewServer(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestMetrics(t *testing.T) {
+	t.Run("counts transitions, rejections and step errors", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		metrics := fsmprom.New[orderState, orderTrigger, orderPayload](fsmprom.Options{
+			ConstLabels: prometheus.Labels{"spec": "order"},
+		})
+		registry := prometheus.NewRegistry()
+		require.NoError(registry.Register(metrics))
+		spec := newMeteredSpec(metrics)
+
+		/* ---------------------------------- When ---------------------------------- */
+		require.Error(fsm.New(spec, created).Fire(t.Context(), pay, orderPayload{failPay: true}))
+		machine := fsm.New(spec, created)
+		require.NoError(machine.Fire(t.Context(), pay, orderPayload{}))
+		require.Error(machine.Fire(t.Context(), ship, orderPayload{}))
+		require.Error(machine.Fire(t.Context(), refund, orderPayload{}))
+		require.NoError(machine.Fire(t.Context(), ship, orderPayload{inStock: true}))
+		require.Error(machine.Fire(t.Context(), refund, orderPayload{}))
+
+		/* ---------------------------------- Then ---------------------------------- */
+		text := scrape(t, registry)
+		for _, line := range []string{
+			`fsm_transitions_total{from="Created",spec="order",to="Paid",trigger="Pay"} 1`,
+			`fsm_transitions_total{from="Paid",spec="order",to="Shipped",trigger="Ship"} 1`,
+			`fsm_rejections_total{result="rejected",spec="order",state="Paid",trigger="Ship"} 1`,
+			`fsm_rejections_total{result="not_found",spec="order",state="Paid",trigger="Refund"} 1`,
+			`fsm_rejections_total{result="forbidden",spec="order",state="Shipped",trigger="Refund"} 1`,
+			`fsm_step_errors_total{phase="action",spec="order",state="Created"} 1`,
+			`fsm_fire_duration_seconds_count{result="failed",spec="order",trigger="Pay"} 1`,
+			`fsm_fire_duration_seconds_count{result="transitioned",spec="order",trigger="Pay"} 1`,
+			`fsm_step_duration_seconds_count{phase="action",spec="order",state="Created"} 2`,
+			`fsm_step_duration_seconds_count{phase="entry",spec="order",state="Paid"} 1`,
+		} {
+			require.Contains(text, line+"\n")
+		}
+		require.Contains(text, `fsm_step_errors_total{phase="entry",spec="order",state="Paid"} 0`+"\n")
+	})
+
+	t.Run("caps label cardinality", func(t *testing.T) {
+		require := require.New(t)
+
+		metrics := fsmprom.New[orderState, orderTrigger, orderPayload](fsmprom.Options{Namespace: "orders", MaxLabelValues: 1})
+		registry := prometheus.NewRegistry()
+		require.NoError(registry.Register(metrics))
+		spec := newMeteredSpec(metrics)
+		machine := fsm.New(spec, created)
+
+		require.NoError(machine.Fire(t.Context(), pay, orderPayload{}))
+		require.NoError(machine.Fire(t.Context(), ship, orderPayload{inStock: true}))
+
+		text := scrape(t, registry)
+		require.Contains(text, `orders_transitions_total{from="Created",to="Paid",trigger="Pay"} 1`+"\n")
+		require.Contains(text, `orders_transitions_total{from="other",to="other",trigger="other"} 1`+"\n")
+		require.False(strings.Contains(text, `"Shipped"`), "values beyond the limit must not be exported")
+	})
+}
@@ -0,0 +1,160 @@
+// Package fsmspec loads fsm specs from declarative YAML or JSON documents, so that workflows can be changed without
+// recompiling. States, triggers, conditions and actions are referred to by name and resolved through an
+// fsm.Registry. It lives in its own module so that the fsm core stays free of dependencies.
+package fsmspec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document is a declarative spec. As YAML:
+//
+//	states:
+//	  - Created
+//	  - name: Fulfilment
+//	    initial: Packing
+//	    onEntry: [reserveStock]
+//	  - name: Packing
+//	    parent: Fulfilment
+//	triggers: [Pay, Cancel]
+//	transitions:
+//	  - from: Created
+//	    on: Pay
+//	    branches:
+//	      - to: Fulfilment
+//	        when: cardValid
+//	        do: chargeCard
+//	      - to: Rejected
+//	  - from: Fulfilment
+//	    on: Cancel
+//	    forbid: already being packed
+//
+// JSON documents use the same field names.
+type Document struct {
+	// States declares every state, in value order. A state without a parent, initial substate or hooks can be
+	// written as just its name.
+	States []State `yaml:"states"`
+	// Triggers optionally declares every trigger, in value order. If present, transitions may only use these.
+	Triggers []Name `yaml:"triggers"`
+	// Transitions declares the transitions and Ignore and Forbid decisions.
+	Transitions []Transition `yaml:"transitions"`
+}
+
+// State declares a state.
+type State struct {
+	Name    string   `yaml:"name"`
+	Parent  string   `yaml:"parent"`
+	Initial string   `yaml:"initial"`
+	OnEntry []string `yaml:"onEntry"` // entry hooks, by action name, in order
+	OnExit  []string `yaml:"onExit"`  // exit hooks, by action name, in order
+	Line    int      `yaml:"-"`       // where the state is declared
+}
+
+// Transition declares what a trigger does in a state: either branches, given as Branches or as a single branch
+// inline, or an Ignore or Forbid decision.
+type Transition struct {
+	From     string   `yaml:"from"`
+	On       string   `yaml:"on"`
+	Branches []Branch `yaml:"branches"` // evaluated in order; only the last may omit When
+	To       string   `yaml:"to"`       // the inline branch's target
+	When     string   `yaml:"when"`     // the inline branch's condition name
+	Do       string   `yaml:"do"`       // the inline branch's action name
+	Ignore   bool     `yaml:"ignore"`
+	Forbid   string   `yaml:"forbid"` // the reason the trigger is forbidden
+	Line     int      `yaml:"-"`      // where the transition is declared
+}
+
+// Branch is one branch of a transition.
+type Branch struct {
+	To   string `yaml:"to"`
+	When string `yaml:"when"` // condition name; empty means unconditional
+	Do   string `yaml:"do"`   // action name; empty means none
+	Line int    `yaml:"-"`    // where the branch is declared
+}
+
+// Name is a name with the line it appears on.
+type Name struct {
+	Value string
+	Line  int
+}
+
+// AllBranches returns the transition's branches: Branches, or the inline branch if To is set.
+func (t Transition) AllBranches() []Branch {
+	if t.To == "" {
+		return t.Branches
+	}
+	return []Branch{{To: t.To, When: t.When, Do: t.Do, Line: t.Line}}
+}
+
+// UnmarshalYAML decodes a state written as a mapping or as just its name, and records its line.
+func (s *State) UnmarshalYAML(node *yaml.Node) error {
+	s.Line = node.Line
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&s.Name)
+	}
+	type plain State
+	return decodeMapping(node, (*plain)(s), "name", "parent", "initial", "onEntry", "onExit")
+}
+
+// UnmarshalYAML decodes the transition and records its line.
+func (t *Transition) UnmarshalYAML(node *yaml.Node) error {
+	t.Line = node.Line
+	type plain Transition
+	return decodeMapping(node, (*plain)(t), "from", "on", "branches", "to", "when", "do", "ignore", "forbid")
+}
+
+// UnmarshalYAML decodes the branch and records its line.
+func (b *Branch) UnmarshalYAML(node *yaml.Node) error {
+	b.Line = node.Line
+	type plain Branch
+	return decodeMapping(node, (*plain)(b), "to", "when", "do")
+}
+
+// UnmarshalYAML decodes the name and records its line.
+func (n *Name) UnmarshalYAML(node *yaml.Node) error {
+	n.Line = node.Line
+	return node.Decode(&n.Value)
+}
+
+// decodeMapping decodes the mapping node into v, rejecting keys other than fields. Problems are returned as a
+// *yaml.TypeError, which the decoder collects and continues past, so that every problem in a document is reported.
+func decodeMapping(node *yaml.Node, v any, fields ...string) error {
+	if node.Kind != yaml.MappingNode {
+		return &yaml.TypeError{Errors: []string{fmt.Sprintf("line %d: expected a mapping", node.Line)}}
+	}
+	var unknown []string
+	for i := 0; i < len(node.Content); i += 2 {
+		if key := node.Content[i]; !slices.Contains(fields, key.Value) {
+			unknown = append(unknown, fmt.Sprintf("line %d: unknown field %q", key.Line, key.Value))
+		}
+	}
+	if len(unknown) > 0 {
+		return &yaml.TypeError{Errors: unknown}
+	}
+	return node.Decode(v)
+}
+
+// Parse reads a YAML or JSON document. Syntax errors and unknown fields are reported with line numbers; every
+// unknown field is reported, joined. Parse does not check references between states; Load does.
+func Parse(r io.Reader) (*Document, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	var doc Document
+	if err := dec.Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			errs := make([]error, len(typeErr.Errors))
+			for i, msg := range typeErr.Errors {
+				errs[i] = errors.New(msg)
+			}
+			return nil, fmt.Errorf("parsing spec: %w", errors.Join(errs...))
+		}
+		return nil, fmt.Errorf("parsing spec: %w", err)
+	}
+	return &doc, nil
+}
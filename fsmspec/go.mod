@@ -0,0 +1,16 @@
+module github.com/tobbstr/fsm/fsmspec
+
+go 1.24.6
+
+require github.com/tobbstr/fsm v0.0.0
+
+require gopkg.in/yaml.v3 v3.0.1
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/stretchr/testify v1.11.0
+)
+
+replace github.com/tobbstr/fsm => ../
@@ -0,0 +1,134 @@
+package fsmspec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/tobbstr/fsm"
+)
+
+// Load reads a YAML or JSON document and builds its Spec, resolving names through registry. Every problem, from syntax
+// errors and unknown fields to invalid references and names missing from the registry (wrapping
+// fsm.ErrUnresolvedName), is reported at once, joined, with the line it is on.
+func Load[S, T ~uint, Payload any](r io.Reader, registry fsm.Registry[S, T, Payload]) (*fsm.Spec[S, T, Payload], error) {
+	doc, err := Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	builder := fsm.NewBuilder[S, T, Payload]()
+	if err := Apply(doc, builder, registry); err != nil {
+		return nil, err
+	}
+	return build(builder)
+}
+
+// Apply validates doc and declares it on builder, resolving names through registry, so that a loaded spec can be
+// extended in code, e.g. with timeouts and observers, before Build. It reports problems like Load; on error, builder
+// may be partially configured and should be discarded.
+func Apply[S, T ~uint, Payload any](doc *Document, builder *fsm.Builder[S, T, Payload], registry fsm.Registry[S, T, Payload]) error {
+	// Keep going after validation errors, so that names missing from the registry are reported too.
+	invalid := doc.Validate()
+	l := &loader[S, T, Payload]{registry: &registry}
+	for _, st := range doc.States {
+		if st.Name == "" {
+			continue
+		}
+		state := l.state(st.Line, st.Name)
+		if st.Parent != "" {
+			builder.From(state).WithParent(l.state(st.Line, st.Parent))
+		}
+		if st.Initial != "" {
+			builder.From(state).WithInitial(l.state(st.Line, st.Initial))
+		}
+		for _, name := range st.OnEntry {
+			builder.From(state).OnEntry(name, l.action(st.Line, name))
+		}
+		for _, name := range st.OnExit {
+			builder.From(state).OnExit(name, l.action(st.Line, name))
+		}
+	}
+	for _, t := range doc.Transitions {
+		if t.From == "" || t.On == "" {
+			continue
+		}
+		from, trigger := l.state(t.Line, t.From), l.trigger(t.Line, t.On)
+		switch {
+		case t.Ignore:
+			builder.From(from).Ignore(trigger)
+		case t.Forbid != "":
+			builder.From(from).Forbid(trigger, t.Forbid)
+		default:
+			branches := slices.DeleteFunc(t.AllBranches(), func(br Branch) bool { return br.To == "" })
+			if len(branches) == 0 {
+				continue
+			}
+			step := builder.From(from).On(trigger).To(l.state(branches[0].Line, branches[0].To))
+			for i, br := range branches {
+				if i > 0 {
+					step = step.To(l.state(br.Line, br.To))
+				}
+				if br.When != "" {
+					step.When(br.When, l.condition(br.Line, br.When))
+				}
+				if br.Do != "" {
+					step.Do(br.Do, l.action(br.Line, br.Do))
+				}
+			}
+		}
+	}
+	return errors.Join(append([]error{invalid}, l.errs...)...)
+}
+
+// build builds the spec, returning a Build panic on a problem that Validate does not detect as an error.
+func build[S, T ~uint, Payload any](builder *fsm.Builder[S, T, Payload]) (spec *fsm.Spec[S, T, Payload], err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("building spec: %v", r)
+		}
+	}()
+	return builder.Build(), nil
+}
+
+// loader looks names up in a registry, collecting an error with the line of each name that is missing rather than
+// stopping at the first.
+type loader[S, T ~uint, Payload any] struct {
+	registry *fsm.Registry[S, T, Payload]
+	errs     []error
+}
+
+// state returns the state called name, referred to on line.
+func (l *loader[S, T, Payload]) state(line int, name string) S {
+	v, err := l.registry.State(name)
+	l.check(line, err)
+	return v
+}
+
+// trigger returns the trigger called name.
+func (l *loader[S, T, Payload]) trigger(line int, name string) T {
+	v, err := l.registry.Trigger(name)
+	l.check(line, err)
+	return v
+}
+
+// condition returns the condition called name.
+func (l *loader[S, T, Payload]) condition(line int, name string) fsm.Condition[Payload] {
+	v, err := l.registry.Condition(name)
+	l.check(line, err)
+	return v
+}
+
+// action returns the action called name.
+func (l *loader[S, T, Payload]) action(line int, name string) fsm.Action[Payload] {
+	v, err := l.registry.Action(name)
+	l.check(line, err)
+	return v
+}
+
+// check records err, if any, on line.
+func (l *loader[S, T, Payload]) check(line int, err error) {
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("line %d: %w", line, err))
+	}
+}
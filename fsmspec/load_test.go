@@ -0,0 +1,301 @@
+package fsmspec_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tobbstr/fsm"
+	"github.com/tobbstr/fsm/fsmspec"
+)
+
+type orderState uint
+
+const (
+	created orderState = iota
+	fulfilment
+	packing
+	shipped
+	rejected
+)
+
+func (s orderState) String() string {
+	return [...]string{"Created", "Fulfilment", "Packing", "Shipped", "Rejected"}[s]
+}
+
+type orderTrigger uint
+
+const (
+	pay orderTrigger = iota
+	ship
+	cancel
+)
+
+func (t orderTrigger) String() string {
+	return [...]string{"Pay", "Ship", "Cancel"}[t]
+}
+
+type order struct {
+	cardValid bool
+	log       *[]string
+}
+
+func newRegistry() fsm.Registry[orderState, orderTrigger, order] {
+	record := func(name string) fsm.Action[order] {
+		return func(_ context.Context, o order) error {
+			*o.log = append(*o.log, name)
+			return nil
+		}
+	}
+	return fsm.Registry[orderState, orderTrigger, order]{
+		States:     fsm.Names(created, fulfilment, packing, shipped, rejected),
+		Triggers:   fsm.Names(pay, ship, cancel),
+		Conditions: map[string]fsm.Condition[order]{"cardValid": func(o order) bool { return o.cardValid }},
+		Actions: map[string]fsm.Action[order]{
+			"chargeCard": record("chargeCard"), "reserveStock": record("reserveStock"),
+		},
+	}
+}
+
+const orderYAML = `
+states:
+  - Created
+  - name: Fulfilment
+    initial: Packing
+    onEntry: [reserveStock]
+  - name: Packing
+    parent: Fulfilment
+  - Shipped
+  - Rejected
+triggers: [Pay, Ship, Cancel]
+transitions:
+  - from: Created
+    on: Pay
+    branches:
+      - to: Fulfilment
+        when: cardValid
+        do: chargeCard
+      - to: Rejected
+  - from: Fulfilment
+    on: Ship
+    to: Shipped
+  - from: Fulfilment
+    on: Cancel
+    forbid: already being packed
+  - from: Shipped
+    on: Pay
+    ignore: true
+`
+
+func TestLoad(t *testing.T) {
+	t.Run("builds a spec from YAML", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		var log []string
+
+		/* ---------------------------------- When ---------------------------------- */
+		spec, err := fsmspec.Load(strings.NewReader(orderYAML), newRegistry())
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.NoError(err)
+		machine := fsm.New(spec, created)
+		require.NoError(machine.Fire(t.Context(), pay, order{cardValid: true, log: &log}))
+		require.Equal([]orderState{packing, fulfilment}, machine.ActiveHierarchy())
+		require.Equal([]string{"chargeCard", "reserveStock"}, log)
+		require.ErrorIs(machine.Fire(t.Context(), cancel, order{log: &log}), fsm.ErrTransitionForbidden)
+		require.NoError(machine.Fire(t.Context(), ship, order{log: &log}))
+		require.NoError(machine.Fire(t.Context(), pay, order{log: &log}))
+		require.Equal(shipped, machine.State())
+
+		rejecting := fsm.New(spec, created)
+		require.NoError(rejecting.Fire(t.Context(), pay, order{log: &log}))
+		require.Equal(rejected, rejecting.State())
+	})
+
+	t.Run("builds a spec from JSON", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		doc := `{
+  "states": ["Created", "Shipped"],
+  "transitions": [{"from": "Created", "on": "Ship", "to": "Shipped"}]
+}`
+
+		/* ---------------------------------- When ---------------------------------- */
+		spec, err := fsmspec.Load(strings.NewReader(doc), newRegistry())
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.NoError(err)
+		machine := fsm.New(spec, created)
+		require.NoError(machine.Fire(t.Context(), ship, order{}))
+		require.Equal(shipped, machine.State())
+	})
+
+	t.Run("reports every unresolved name with its line", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		doc := `
+states:
+  - Created
+  - name: Lost
+    onEntry: [notify]
+transitions:
+  - from: Created
+    on: Teleport
+    to: Lost
+    when: lucky
+`
+
+		/* ---------------------------------- When ---------------------------------- */
+		_, err := fsmspec.Load(strings.NewReader(doc), newRegistry())
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.ErrorIs(err, fsm.ErrUnresolvedName)
+		require.EqualError(err, strings.Join([]string{
+			`line 4: unresolved name: state "Lost"`,
+			`line 4: unresolved name: action "notify"`,
+			`line 7: unresolved name: trigger "Teleport"`,
+			`line 7: unresolved name: state "Lost"`,
+			`line 7: unresolved name: condition "lucky"`,
+		}, "\n"))
+	})
+
+	t.Run("reports every validation error with its line", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		doc := `
+states:
+  - Created
+  - Created
+  - name: Fulfilment
+    initial: Shipped
+  - Shipped
+triggers: [Pay]
+transitions:
+  - from: Created
+    on: Pay
+    branches:
+      - to: Fulfilment
+      - to: Rejected
+        when: cardValid
+  - from: Created
+    on: Pay
+    ignore: true
+  - from: Shipped
+    on: Ship
+  - on: Cancel
+`
+
+		/* ---------------------------------- When ---------------------------------- */
+		_, err := fsmspec.Load(strings.NewReader(doc), newRegistry())
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.EqualError(err, strings.Join([]string{
+			`line 4: state "Created" is declared more than once`,
+			`line 5: initial state "Shipped" of "Fulfilment" is not its substate`,
+			`line 13: unconditional branch to "Fulfilment" must be the last`,
+			`line 14: undeclared state "Rejected"`,
+			`line 16: transition from "Created" on "Pay" is already declared on line 10`,
+			`line 19: undeclared trigger "Ship"`,
+			`line 19: transition from "Shipped" on "Ship" needs to, branches, ignore or forbid`,
+			`line 21: transition needs both from and on`,
+		}, "\n"))
+	})
+
+	t.Run("reports unresolved names together with validation errors", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		doc := `
+states:
+  - Created
+transitions:
+  - from: Created
+    on: Pay
+    to: Nope
+  - from: Created
+    on: Ship
+    to: Created
+    do: missingAction
+`
+
+		/* ---------------------------------- When ---------------------------------- */
+		_, err := fsmspec.Load(strings.NewReader(doc), newRegistry())
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.ErrorIs(err, fsm.ErrUnresolvedName)
+		require.EqualError(err, strings.Join([]string{
+			`line 5: undeclared state "Nope"`,
+			`line 5: unresolved name: state "Nope"`,
+			`line 8: unresolved name: action "missingAction"`,
+		}, "\n"))
+	})
+
+	t.Run("reports parent cycles", func(t *testing.T) {
+		doc := `
+states:
+  - name: Created
+    parent: Shipped
+  - name: Shipped
+    parent: Created
+`
+
+		_, err := fsmspec.Load(strings.NewReader(doc), newRegistry())
+
+		require.EqualError(t, err, strings.Join([]string{
+			`line 3: state "Created" is its own ancestor`,
+			`line 5: state "Shipped" is its own ancestor`,
+		}, "\n"))
+	})
+
+	t.Run("reports every unknown field with its line", func(t *testing.T) {
+		doc := `
+states:
+  - name: Created
+    colour: blue
+transitions:
+  - from: Created
+    on: Pay
+    goto: Shipped
+`
+
+		_, err := fsmspec.Load(strings.NewReader(doc), newRegistry())
+
+		require.EqualError(t, err, "parsing spec: "+strings.Join([]string{
+			`line 4: unknown field "colour"`,
+			`line 8: unknown field "goto"`,
+		}, "\n"))
+	})
+
+	t.Run("reports syntax errors", func(t *testing.T) {
+		_, err := fsmspec.Load(strings.NewReader("states: [Created"), newRegistry())
+
+		require.ErrorContains(t, err, "parsing spec: yaml: line 1")
+	})
+}
+
+func TestApply(t *testing.T) {
+	require := require.New(t)
+
+	/* ---------------------------------- Given --------------------------------- */
+	doc, err := fsmspec.Parse(strings.NewReader(orderYAML))
+	require.NoError(err)
+	builder := fsm.NewBuilder[orderState, orderTrigger, order]()
+	var transitions int
+	builder.Observe(fsm.Observer[orderState, orderTrigger, order]{
+		OnTransition: func(context.Context, fsm.TransitionEvent[orderState, orderTrigger, order]) { transitions++ },
+	})
+
+	/* ---------------------------------- When ---------------------------------- */
+	err = fsmspec.Apply(doc, builder, newRegistry())
+
+	/* ---------------------------------- Then ---------------------------------- */
+	require.NoError(err)
+	machine := fsm.New(builder.Build(), fulfilment)
+	require.NoError(machine.Fire(t.Context(), ship, order{}))
+	require.Equal(1, transitions)
+}
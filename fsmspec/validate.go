@@ -0,0 +1,123 @@
+package fsmspec
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// maxDepth is the deepest nesting of states that fsm supports.
+const maxDepth = 10
+
+// Validate checks the document for problems that Builder.Build would panic on, and for references to undeclared
+// states and triggers. Every problem is reported, joined, with the line it is on.
+func (doc *Document) Validate() error {
+	v := &validator{}
+	states := make(map[string]State, len(doc.States))
+	for _, st := range doc.States {
+		switch _, dup := states[st.Name]; {
+		case st.Name == "":
+			v.fail(st.Line, "state has no name")
+		case dup:
+			v.fail(st.Line, "state %q is declared more than once", st.Name)
+		default:
+			states[st.Name] = st
+		}
+	}
+	declared := func(line int, name string) {
+		if _, ok := states[name]; !ok {
+			v.fail(line, "undeclared state %q", name)
+		}
+	}
+	for _, st := range doc.States {
+		if st.Parent != "" {
+			declared(st.Line, st.Parent)
+		}
+		if st.Initial != "" {
+			declared(st.Line, st.Initial)
+			if initial, ok := states[st.Initial]; ok && initial.Parent != st.Name {
+				v.fail(st.Line, "initial state %q of %q is not its substate", st.Initial, st.Name)
+			}
+		}
+		var path []string
+		for name := st.Name; name != ""; name = states[name].Parent {
+			if slices.Contains(path, name) {
+				v.fail(st.Line, "state %q is its own ancestor", st.Name)
+				break
+			}
+			if path = append(path, name); len(path) > maxDepth {
+				v.fail(st.Line, "state %q is nested deeper than %d levels", st.Name, maxDepth)
+				break
+			}
+		}
+	}
+
+	triggers := make(map[string]bool, len(doc.Triggers))
+	for _, t := range doc.Triggers {
+		if triggers[t.Value] {
+			v.fail(t.Line, "trigger %q is declared more than once", t.Value)
+		}
+		triggers[t.Value] = true
+	}
+	type pair struct{ from, on string }
+	decided := make(map[pair]int)
+	for _, t := range doc.Transitions {
+		if t.From == "" || t.On == "" {
+			v.fail(t.Line, "transition needs both from and on")
+			continue
+		}
+		declared(t.Line, t.From)
+		if len(doc.Triggers) > 0 && !triggers[t.On] {
+			v.fail(t.Line, "undeclared trigger %q", t.On)
+		}
+		if line, dup := decided[pair{t.From, t.On}]; dup {
+			v.fail(t.Line, "transition from %q on %q is already declared on line %d", t.From, t.On, line)
+		} else {
+			decided[pair{t.From, t.On}] = t.Line
+		}
+		v.checkDecision(t, declared)
+	}
+	return errors.Join(v.errs...)
+}
+
+// checkDecision checks that the transition declares exactly one of branches, an inline branch, ignore and forbid, and
+// that only its last branch is unconditional.
+func (v *validator) checkDecision(t Transition, declared func(line int, name string)) {
+	kinds := 0
+	for _, set := range []bool{len(t.Branches) > 0, t.To != "", t.Ignore, t.Forbid != ""} {
+		if set {
+			kinds++
+		}
+	}
+	switch {
+	case kinds == 0:
+		v.fail(t.Line, "transition from %q on %q needs to, branches, ignore or forbid", t.From, t.On)
+		return
+	case kinds > 1:
+		v.fail(t.Line, "transition from %q on %q declares more than one of to, branches, ignore and forbid", t.From, t.On)
+		return
+	case t.To == "" && (t.When != "" || t.Do != ""):
+		v.fail(t.Line, "transition from %q on %q has when or do without to", t.From, t.On)
+	}
+	branches := t.AllBranches()
+	for i, br := range branches {
+		if br.To == "" {
+			v.fail(br.Line, "branch has no to")
+			continue
+		}
+		declared(br.Line, br.To)
+		if br.When == "" && i < len(branches)-1 {
+			v.fail(br.Line, "unconditional branch to %q must be the last", br.To)
+		}
+	}
+}
+
+// validator collects the problems of a document.
+type validator struct {
+	errs []error
+}
+
+// fail records a problem on line.
+func (v *validator) fail(line int, format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...)))
+}
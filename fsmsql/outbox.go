@@ -0,0 +1,117 @@
+package fsmsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/tobbstr/fsm"
+)
+
+// OutboxStore is a fsm.OutboxStore backed by a database/sql table with the columns id, topic, msg_key and body; see
+// Schema. Bind it with WithTx to the transaction the machine is saved in, so that messages written with
+// fsm.Outbox.WriteTo commit or roll back with the transition, and drain it with an fsm.Relay.
+type OutboxStore struct {
+	q   Querier
+	cfg config
+}
+
+var _ fsm.OutboxStore = (*OutboxStore)(nil)
+
+// NewOutboxStore creates an OutboxStore running its queries on q. The default table is "fsm_outbox"; WithSnapshots
+// has no effect.
+func NewOutboxStore(q Querier, opts ...Option) *OutboxStore {
+	cfg := config{table: "fsm_outbox", placeholder: Question}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &OutboxStore{q: q, cfg: cfg}
+}
+
+// WithTx returns a copy of the store running its queries in tx.
+func (s *OutboxStore) WithTx(tx *sql.Tx) *OutboxStore {
+	return &OutboxStore{q: tx, cfg: s.cfg}
+}
+
+// Schema returns the CREATE TABLE statement for the store's table. idColumn is the definition of the auto-incrementing
+// id column, e.g. "BIGSERIAL PRIMARY KEY" (PostgreSQL), "BIGINT AUTO_INCREMENT PRIMARY KEY" (MySQL) or
+// "INTEGER PRIMARY KEY AUTOINCREMENT" (SQLite); bodyType is the binary type of the body column, e.g. "BYTEA" or "BLOB".
+func (s *OutboxStore) Schema(idColumn, bodyType string) string {
+	return "CREATE TABLE IF NOT EXISTS " + s.cfg.table + " (\n" +
+		"\tid " + idColumn + ",\n" +
+		"\ttopic VARCHAR(255) NOT NULL,\n" +
+		"\tmsg_key VARCHAR(255) NOT NULL,\n" +
+		"\tbody " + bodyType + " NOT NULL\n" +
+		")"
+}
+
+// CreateTable creates the store's table, if it does not exist, with Schema(idColumn, bodyType).
+func (s *OutboxStore) CreateTable(ctx context.Context, idColumn, bodyType string) error {
+	if _, err := s.q.ExecContext(ctx, s.Schema(idColumn, bodyType)); err != nil {
+		return fmt.Errorf("creating table %s: %w", s.cfg.table, err)
+	}
+	return nil
+}
+
+// Append inserts the messages in order.
+func (s *OutboxStore) Append(ctx context.Context, messages []fsm.Message) error {
+	p := s.cfg.placeholder
+	query := "INSERT INTO " + s.cfg.table + " (topic, msg_key, body) VALUES (" + p(1) + ", " + p(2) + ", " + p(3) + ")"
+	for _, m := range messages {
+		body := m.Body
+		if body == nil {
+			body = []byte{} // the column is NOT NULL
+		}
+		if _, err := s.q.ExecContext(ctx, query, m.Topic, m.Key, body); err != nil {
+			return fmt.Errorf("appending outbox message: %w", err)
+		}
+	}
+	return nil
+}
+
+// Pending returns up to limit stored messages, ordered by ID.
+func (s *OutboxStore) Pending(ctx context.Context, limit int) ([]fsm.OutboxEntry, error) {
+	if limit < 1 {
+		return nil, nil // a negative LIMIT means no limit in some databases
+	}
+	rows, err := s.q.QueryContext(ctx, "SELECT id, topic, msg_key, body FROM "+s.cfg.table+" ORDER BY id LIMIT "+s.cfg.placeholder(1), limit)
+	if err != nil {
+		return nil, fmt.Errorf("loading pending outbox messages: %w", err)
+	}
+	defer rows.Close()
+	var entries []fsm.OutboxEntry
+	for rows.Next() {
+		var (
+			e  fsm.OutboxEntry
+			id int64
+		)
+		if err := rows.Scan(&id, &e.Topic, &e.Key, &e.Body); err != nil {
+			return nil, fmt.Errorf("loading pending outbox messages: %w", err)
+		}
+		e.ID = uint64(id)
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("loading pending outbox messages: %w", err)
+	}
+	return entries, nil
+}
+
+// MarkPublished deletes the messages with the IDs.
+func (s *OutboxStore) MarkPublished(ctx context.Context, ids []uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	params := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		params[i] = s.cfg.placeholder(i + 1)
+		args[i] = int64(id)
+	}
+	query := "DELETE FROM " + s.cfg.table + " WHERE id IN (" + strings.Join(params, ", ") + ")"
+	if _, err := s.q.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("marking outbox messages published: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package fsmsql_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tobbstr/fsm"
+	"github.com/tobbstr/fsm/fsmsql"
+	"github.com/tobbstr/fsm/fsmtest"
+)
+
+func TestOutboxStore(t *testing.T) {
+	b := fsm.NewBuilder[orderState, orderTrigger, orderPayload]()
+	b.From(paid).OnEntry("announce payment", func(ctx context.Context, _ orderPayload) error {
+		return fsm.Enqueue(ctx, fsm.Message{Topic: "payments", Key: "order-1", Body: []byte("paid")})
+	})
+	b.From(awaitingPayment).On(pay).To(paid)
+	spec := b.Build()
+
+	setup := func(t *testing.T) (*fsmsql.Store[string, orderState, orderTrigger], *fsmsql.OutboxStore, *sql.DB) {
+		store, db := newStore(t)
+		outboxStore := fsmsql.NewOutboxStore(db)
+		require.NoError(t, outboxStore.CreateTable(t.Context(), "INTEGER PRIMARY KEY AUTOINCREMENT", "BLOB"))
+		_, err := fsm.NewRepository(spec, store).Create(t.Context(), "order-1", awaitingPayment)
+		require.NoError(t, err)
+		return store, outboxStore, db
+	}
+
+	// fireInTx fires pay and writes the outbox in one transaction, then commits or rolls back.
+	fireInTx := func(t *testing.T, store *fsmsql.Store[string, orderState, orderTrigger], outboxStore *fsmsql.OutboxStore, db *sql.DB, commit bool) {
+		tx, err := db.BeginTx(t.Context(), nil)
+		require.NoError(t, err)
+		ctx, outbox := fsm.WithOutbox(t.Context())
+		_, err = fsm.NewRepository(spec, store.WithTx(tx)).Fire(ctx, "order-1", pay, orderPayload{tx: tx})
+		require.NoError(t, err)
+		require.NoError(t, outbox.WriteTo(ctx, outboxStore.WithTx(tx)))
+		if commit {
+			require.NoError(t, tx.Commit())
+		} else {
+			require.NoError(t, tx.Rollback())
+		}
+	}
+
+	t.Run("relays messages committed with the transition", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		store, outboxStore, db := setup(t)
+		fireInTx(t, store, outboxStore, db, true)
+		publisher := &fsmtest.Publisher{}
+
+		/* ---------------------------------- When ---------------------------------- */
+		published, err := fsm.NewRelay(outboxStore, publisher).Drain(t.Context())
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.NoError(err)
+		require.Equal(1, published)
+		require.Equal([]fsm.Message{{Topic: "payments", Key: "order-1", Body: []byte("paid")}}, publisher.Published())
+		pending, err := outboxStore.Pending(t.Context(), 10)
+		require.NoError(err)
+		require.Empty(pending)
+	})
+
+	t.Run("drops messages rolled back with the transition", func(t *testing.T) {
+		require := require.New(t)
+
+		store, outboxStore, db := setup(t)
+
+		fireInTx(t, store, outboxStore, db, false)
+
+		pending, err := outboxStore.Pending(t.Context(), 10)
+		require.NoError(err)
+		require.Empty(pending)
+	})
+
+	t.Run("returns pending entries in order, up to the limit", func(t *testing.T) {
+		require := require.New(t)
+
+		_, outboxStore, _ := setup(t)
+		require.NoError(outboxStore.Append(t.Context(), []fsm.Message{{Topic: "a", Body: []byte("1")}, {Topic: "b", Key: "k", Body: []byte("2")}, {Topic: "c", Body: []byte("3")}}))
+
+		pending, err := outboxStore.Pending(t.Context(), 2)
+		require.NoError(err)
+		require.Equal([]fsm.OutboxEntry{
+			{ID: 1, Message: fsm.Message{Topic: "a", Body: []byte("1")}},
+			{ID: 2, Message: fsm.Message{Topic: "b", Key: "k", Body: []byte("2")}},
+		}, pending)
+
+		require.NoError(outboxStore.MarkPublished(t.Context(), []uint64{1, 2}))
+		pending, err = outboxStore.Pending(t.Context(), 10)
+		require.NoError(err)
+		require.Equal([]fsm.OutboxEntry{{ID: 3, Message: fsm.Message{Topic: "c", Body: []byte("3")}}}, pending)
+	})
+}
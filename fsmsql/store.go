@@ -0,0 +1,184 @@
+// Package fsmsql persists fsm machines with database/sql. Store implements fsm.Store on a table holding each machine's
+// state, version and, optionally, its full snapshot, with optimistic locking on the version. Bind it to the caller's
+// transaction with WithTx so that the machine's new state commits or rolls back together with the actions' writes.
+package fsmsql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/tobbstr/fsm"
+)
+
+// Querier is the subset of *sql.DB, *sql.Tx and *sql.Conn used by Store.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+var (
+	_ Querier = (*sql.DB)(nil)
+	_ Querier = (*sql.Tx)(nil)
+	_ Querier = (*sql.Conn)(nil)
+)
+
+// Placeholder returns the bind parameter for the nth (1-based) argument of a query.
+type Placeholder func(n int) string
+
+// Question is the placeholder style of MySQL and SQLite: ?.
+func Question(int) string { return "?" }
+
+// Dollar is the placeholder style of PostgreSQL: $1, $2, ...
+func Dollar(n int) string { return "$" + strconv.Itoa(n) }
+
+// Option configures a Store.
+type Option func(*config)
+
+type config struct {
+	table        string
+	placeholder  Placeholder
+	snapshots    bool
+	insertIgnore bool
+}
+
+// WithTable sets the table name. The default is "fsm_machines". The name is inserted into queries verbatim.
+func WithTable(table string) Option {
+	return func(c *config) { c.table = table }
+}
+
+// WithPlaceholder sets the bind parameter style. The default is Question.
+func WithPlaceholder(placeholder Placeholder) Option {
+	return func(c *config) { c.placeholder = placeholder }
+}
+
+// WithInsertIgnore creates machines with INSERT IGNORE, for MySQL and MariaDB, rather than with INSERT ... ON CONFLICT
+// (id) DO NOTHING, which PostgreSQL and SQLite support.
+func WithInsertIgnore() Option {
+	return func(c *config) { c.insertIgnore = true }
+}
+
+// WithSnapshots stores the full snapshot, including pending deadlines, as JSON next to the state. Without it only the
+// state is stored and loaded snapshots have no deadlines.
+func WithSnapshots() Option {
+	return func(c *config) { c.snapshots = true }
+}
+
+// Store is a fsm.Store backed by a database/sql table with the columns id, state, version and snapshot; see Schema.
+// States are stored as integers, so reordering the state constants invalidates stored rows.
+type Store[ID comparable, S, T ~uint] struct {
+	q   Querier
+	cfg config
+}
+
+var _ fsm.Store[string, uint, uint] = (*Store[string, uint, uint])(nil)
+
+// NewStore creates a Store running its queries on q, usually a *sql.DB.
+func NewStore[ID comparable, S, T ~uint](q Querier, opts ...Option) *Store[ID, S, T] {
+	cfg := config{table: "fsm_machines", placeholder: Question}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Store[ID, S, T]{q: q, cfg: cfg}
+}
+
+// WithTx returns a copy of the store running its queries in tx, so that saves commit or roll back with the caller's
+// other writes.
+func (s *Store[ID, S, T]) WithTx(tx *sql.Tx) *Store[ID, S, T] {
+	return &Store[ID, S, T]{q: tx, cfg: s.cfg}
+}
+
+// Schema returns the CREATE TABLE statement for the store's table, using idType as the SQL type of the id column,
+// e.g. "VARCHAR(64)" or "BIGINT".
+func (s *Store[ID, S, T]) Schema(idType string) string {
+	return "CREATE TABLE IF NOT EXISTS " + s.cfg.table + " (\n" +
+		"\tid " + idType + " PRIMARY KEY,\n" +
+		"\tstate BIGINT NOT NULL,\n" +
+		"\tversion BIGINT NOT NULL,\n" +
+		"\tsnapshot TEXT\n" +
+		")"
+}
+
+// CreateTable creates the store's table, if it does not exist, with Schema(idType).
+func (s *Store[ID, S, T]) CreateTable(ctx context.Context, idType string) error {
+	if _, err := s.q.ExecContext(ctx, s.Schema(idType)); err != nil {
+		return fmt.Errorf("creating table %s: %w", s.cfg.table, err)
+	}
+	return nil
+}
+
+// Load returns the machine's record, or an error wrapping fsm.ErrMachineNotFound.
+func (s *Store[ID, S, T]) Load(ctx context.Context, id ID) (fsm.Record[S, T], error) {
+	var (
+		state    int64
+		version  int64
+		snapshot sql.NullString
+		record   fsm.Record[S, T]
+	)
+	query := "SELECT state, version, snapshot FROM " + s.cfg.table + " WHERE id = " + s.cfg.placeholder(1)
+	err := s.q.QueryRowContext(ctx, query, id).Scan(&state, &version, &snapshot)
+	if errors.Is(err, sql.ErrNoRows) {
+		return record, fsm.ErrMachineNotFound
+	}
+	if err != nil {
+		return record, fmt.Errorf("loading machine %v: %w", id, err)
+	}
+	if snapshot.Valid {
+		if err := json.Unmarshal([]byte(snapshot.String), &record.Snapshot); err != nil {
+			return record, fmt.Errorf("decoding snapshot of machine %v: %w", id, err)
+		}
+	}
+	record.Snapshot.State = S(state) // the state column is authoritative
+	record.Version = uint64(version)
+	return record, nil
+}
+
+// Save stores snapshot as version expected+1 if the stored version is expected, inserting the row when expected is 0.
+// It returns an error wrapping fsm.ErrVersionConflict if no row matched.
+func (s *Store[ID, S, T]) Save(ctx context.Context, id ID, snapshot fsm.Snapshot[S, T], expected uint64) error {
+	var encoded sql.NullString
+	if s.cfg.snapshots {
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			return fmt.Errorf("encoding snapshot of machine %v: %w", id, err)
+		}
+		encoded = sql.NullString{String: string(data), Valid: true}
+	}
+	p, t := s.cfg.placeholder, s.cfg.table
+	state, version := int64(snapshot.State), int64(expected+1)
+
+	var (
+		result sql.Result
+		err    error
+	)
+	if expected == 0 {
+		// Skipping an existing row, even one inserted by a concurrent transaction, reports it as zero rows affected
+		// rather than as a driver-specific unique violation. A NOT EXISTS check would not: concurrent transactions can
+		// all pass it before either inserts.
+		columns := " INTO " + t + " (id, state, version, snapshot) VALUES (" + p(1) + ", " + p(2) + ", " + p(3) + ", " + p(4) + ")"
+		query := "INSERT" + columns + " ON CONFLICT (id) DO NOTHING"
+		if s.cfg.insertIgnore {
+			query = "INSERT IGNORE" + columns
+		}
+		result, err = s.q.ExecContext(ctx, query, id, state, version, encoded)
+	} else {
+		query := "UPDATE " + t + " SET state = " + p(1) + ", version = " + p(2) + ", snapshot = " + p(3) +
+			" WHERE id = " + p(4) + " AND version = " + p(5)
+		result, err = s.q.ExecContext(ctx, query, state, version, encoded, id, int64(expected))
+	}
+	if err != nil {
+		return fmt.Errorf("saving machine %v: %w", id, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("saving machine %v: %w", id, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("saving machine %v with expected version %d: %w", id, expected, fsm.ErrVersionConflict)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package fsmsql_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tobbstr/fsm"
+	"github.com/tobbstr/fsm/fsmsql"
+	"github.com/tobbstr/fsm/fsmtest"
+	_ "modernc.org/sqlite"
+)
+
+type orderState uint
+
+const (
+	awaitingPayment orderState = iota
+	paid
+)
+
+type orderTrigger uint
+
+const pay orderTrigger = iota
+
+type orderPayload struct {
+	tx *sql.Tx
+}
+
+// openDB opens a SQLite database in a temporary file. SQLite allows one writer at a time, so the pool is limited to
+// one connection.
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "fsm.db"))
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// openConcurrentDB opens a SQLite database in a temporary file with several connections, which wait for each other's
+// write locks, so that concurrent saves run on separate connections.
+func openConcurrentDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "fsm.db")+"?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(8)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newStore(t *testing.T, opts ...fsmsql.Option) (*fsmsql.Store[string, orderState, orderTrigger], *sql.DB) {
+	t.Helper()
+	db := openDB(t)
+	store := fsmsql.NewStore[string, orderState, orderTrigger](db, opts...)
+	require.NoError(t, store.CreateTable(t.Context(), "VARCHAR(64)"))
+	return store, db
+}
+
+func TestStore(t *testing.T) {
+	id := func(n int) string { return fmt.Sprint("order-", n) }
+
+	t.Run("conformance", func(t *testing.T) {
+		fsmtest.RunStoreTests(t, func(t *testing.T) fsm.Store[string, orderState, orderTrigger] {
+			store, _ := newStore(t, fsmsql.WithSnapshots())
+			return store
+		}, id)
+	})
+
+	t.Run("conformance with dollar placeholders", func(t *testing.T) {
+		fsmtest.RunStoreTests(t, func(t *testing.T) fsm.Store[string, orderState, orderTrigger] {
+			store, _ := newStore(t, fsmsql.WithSnapshots(), fsmsql.WithPlaceholder(fsmsql.Dollar), fsmsql.WithTable("orders"))
+			return store
+		}, id)
+	})
+
+	t.Run("conformance on concurrent connections", func(t *testing.T) {
+		fsmtest.RunStoreTests(t, func(t *testing.T) fsm.Store[string, orderState, orderTrigger] {
+			store := fsmsql.NewStore[string, orderState, orderTrigger](openConcurrentDB(t), fsmsql.WithSnapshots())
+			require.NoError(t, store.CreateTable(t.Context(), "VARCHAR(64)"))
+			return store
+		}, id)
+	})
+
+	t.Run("creates machines skipping existing rows", func(t *testing.T) {
+		for _, tc := range []struct {
+			opts []fsmsql.Option
+			want string
+		}{
+			{nil, "INSERT INTO fsm_machines (id, state, version, snapshot) VALUES (?, ?, ?, ?) ON CONFLICT (id) DO NOTHING"},
+			{[]fsmsql.Option{fsmsql.WithInsertIgnore()}, "INSERT IGNORE INTO fsm_machines (id, state, version, snapshot) VALUES (?, ?, ?, ?)"},
+		} {
+			q := &recordingQuerier{}
+			store := fsmsql.NewStore[string, orderState, orderTrigger](q, tc.opts...)
+
+			err := store.Save(t.Context(), "order-1", fsm.Snapshot[orderState, orderTrigger]{State: paid}, 0)
+
+			require.ErrorIs(t, err, fsm.ErrVersionConflict, "the querier reports no rows affected")
+			require.Equal(t, []string{tc.want}, q.queries)
+		}
+	})
+
+	t.Run("stores only the state without WithSnapshots", func(t *testing.T) {
+		require := require.New(t)
+
+		store, db := newStore(t)
+		snapshot := fsm.Snapshot[orderState, orderTrigger]{State: paid, Deadlines: []fsm.Deadline[orderState, orderTrigger]{
+			{State: paid, Trigger: pay, At: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		}}
+
+		require.NoError(store.Save(t.Context(), "order-1", snapshot, 0))
+
+		record, err := store.Load(t.Context(), "order-1")
+		require.NoError(err)
+		require.Equal(fsm.Record[orderState, orderTrigger]{Snapshot: fsm.Snapshot[orderState, orderTrigger]{State: paid}, Version: 1}, record)
+		var encoded sql.NullString
+		require.NoError(db.QueryRowContext(t.Context(), "SELECT snapshot FROM fsm_machines").Scan(&encoded))
+		require.False(encoded.Valid)
+	})
+}
+
+func TestStore_WithTx(t *testing.T) {
+	spec := func() *fsm.Spec[orderState, orderTrigger, orderPayload] {
+		b := fsm.NewBuilder[orderState, orderTrigger, orderPayload]()
+		b.From(awaitingPayment).On(pay).To(paid).Do("record payment", func(ctx context.Context, p orderPayload) error {
+			_, err := p.tx.ExecContext(ctx, "INSERT INTO payments (order_id) VALUES ('order-1')")
+			return err
+		})
+		return b.Build()
+	}()
+
+	setup := func(t *testing.T) (*fsmsql.Store[string, orderState, orderTrigger], *sql.DB) {
+		store, db := newStore(t)
+		_, err := db.ExecContext(t.Context(), "CREATE TABLE payments (order_id VARCHAR(64))")
+		require.NoError(t, err)
+		_, err = fsm.NewRepository(spec, store).Create(t.Context(), "order-1", awaitingPayment)
+		require.NoError(t, err)
+		return store, db
+	}
+
+	// fireInTx fires pay in a transaction shared by the action and the store, then commits or rolls back.
+	fireInTx := func(t *testing.T, store *fsmsql.Store[string, orderState, orderTrigger], db *sql.DB, commit bool) {
+		tx, err := db.BeginTx(t.Context(), nil)
+		require.NoError(t, err)
+		_, err = fsm.NewRepository(spec, store.WithTx(tx)).Fire(t.Context(), "order-1", pay, orderPayload{tx: tx})
+		require.NoError(t, err)
+		if commit {
+			require.NoError(t, tx.Commit())
+		} else {
+			require.NoError(t, tx.Rollback())
+		}
+	}
+
+	countPayments := func(t *testing.T, db *sql.DB) int {
+		var n int
+		require.NoError(t, db.QueryRowContext(t.Context(), "SELECT COUNT(*) FROM payments").Scan(&n))
+		return n
+	}
+
+	t.Run("commits the state together with the action's writes", func(t *testing.T) {
+		require := require.New(t)
+		store, db := setup(t)
+
+		fireInTx(t, store, db, true)
+
+		record, err := store.Load(t.Context(), "order-1")
+		require.NoError(err)
+		require.Equal(paid, record.Snapshot.State)
+		require.Equal(uint64(2), record.Version)
+		require.Equal(1, countPayments(t, db))
+	})
+
+	t.Run("rolls back the state together with the action's writes", func(t *testing.T) {
+		require := require.New(t)
+		store, db := setup(t)
+
+		fireInTx(t, store, db, false)
+
+		record, err := store.Load(t.Context(), "order-1")
+		require.NoError(err)
+		require.Equal(awaitingPayment, record.Snapshot.State)
+		require.Equal(uint64(1), record.Version)
+		require.Zero(countPayments(t, db))
+	})
+}
+
+func TestStore_Schema(t *testing.T) {
+	store := fsmsql.NewStore[int64, orderState, orderTrigger](nil, fsmsql.WithTable("orders"))
+
+	require.Equal(t, `CREATE TABLE IF NOT EXISTS orders (
+	id BIGINT PRIMARY KEY,
+	state BIGINT NOT NULL,
+	version BIGINT NOT NULL,
+	snapshot TEXT
+)`, store.Schema("BIGINT"))
+}
+
+// recordingQuerier records the statements it is asked to execute, and reports that they affected no rows.
+type recordingQuerier struct {
+	queries []string
+}
+
+func (q *recordingQuerier) ExecContext(_ context.Context, query string, _ ...any) (sql.Result, error) {
+	q.queries = append(q.queries, query)
+	return driver.RowsAffected(0), nil
+}
+
+func (q *recordingQuerier) QueryContext(context.Context, string, ...any) (*sql.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func (q *recordingQuerier) QueryRowContext(context.Context, string, ...any) *sql.Row {
+	return nil
+}
@@ -0,0 +1,122 @@
+// Package fsmtest provides test doubles for the fsm package.
+package fsmtest
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/tobbstr/fsm"
+)
+
+// Clock is a manually advanced fsm.Clock for deterministic tests. Time only moves when Advance or Sleep is called.
+// Unlike the system clock, due AfterFunc callbacks are run synchronously by the call that advances time, in deadline
+// order, so a test observes their effects as soon as Advance returns.
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*timer
+	sleeps []time.Duration
+}
+
+var _ fsm.Clock = (*Clock)(nil)
+
+// NewClock returns a Clock set to start.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now returns the clock's current time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// AfterFunc schedules f to run once the clock has been advanced by d.
+func (c *Clock) AfterFunc(d time.Duration, f func()) fsm.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &timer{clock: c, deadline: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Sleep records d and advances the clock by it, returning immediately. It returns ctx's error without advancing if
+// ctx is already done.
+func (c *Clock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.sleeps = append(c.sleeps, d)
+	c.mu.Unlock()
+	c.Advance(d)
+	return ctx.Err()
+}
+
+// Sleeps returns the durations passed to Sleep, in call order.
+func (c *Clock) Sleeps() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.sleeps)
+}
+
+// Advance moves the clock forward by d and runs every timer that has become due.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+	for {
+		t := c.popDue()
+		if t == nil {
+			return
+		}
+		t.f()
+	}
+}
+
+// Pending returns the number of timers that have neither fired nor been stopped.
+func (c *Clock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// popDue removes and returns the earliest due timer, or nil if none is due.
+func (c *Clock) popDue() *timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	idx := -1
+	for i, t := range c.timers {
+		if !t.deadline.After(c.now) && (idx < 0 || t.deadline.Before(c.timers[idx].deadline)) {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return nil
+	}
+	t := c.timers[idx]
+	c.timers = slices.Delete(c.timers, idx, idx+1)
+	return t
+}
+
+type timer struct {
+	clock    *Clock
+	deadline time.Time
+	f        func()
+}
+
+// Stop removes the timer if it has not fired yet.
+func (t *timer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	idx := slices.Index(c.timers, t)
+	if idx < 0 {
+		return false
+	}
+	c.timers = slices.Delete(c.timers, idx, idx+1)
+	return true
+}
@@ -0,0 +1,55 @@
+package fsmtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClock(t *testing.T) {
+	t.Run("runs due timers in deadline order when advanced", func(t *testing.T) {
+		require := require.New(t)
+
+		start := time.Unix(100, 0)
+		clock := NewClock(start)
+		var fired []string
+		clock.AfterFunc(2*time.Second, func() { fired = append(fired, "2s") })
+		clock.AfterFunc(time.Second, func() { fired = append(fired, "1s") })
+		clock.AfterFunc(time.Minute, func() { fired = append(fired, "1m") })
+
+		clock.Advance(5 * time.Second)
+
+		require.Equal([]string{"1s", "2s"}, fired)
+		require.Equal(start.Add(5*time.Second), clock.Now())
+		require.Equal(1, clock.Pending())
+	})
+
+	t.Run("stopped timers never fire", func(t *testing.T) {
+		require := require.New(t)
+
+		clock := NewClock(time.Unix(0, 0))
+		fired := false
+		timer := clock.AfterFunc(time.Second, func() { fired = true })
+
+		require.True(timer.Stop())
+		require.False(timer.Stop())
+		clock.Advance(time.Hour)
+		require.False(fired)
+	})
+
+	t.Run("sleep advances the clock and is recorded", func(t *testing.T) {
+		require := require.New(t)
+
+		clock := NewClock(time.Unix(0, 0))
+		require.NoError(clock.Sleep(t.Context(), 3*time.Second))
+		require.Equal(time.Unix(3, 0), clock.Now())
+		require.Equal([]time.Duration{3 * time.Second}, clock.Sleeps())
+
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		require.ErrorIs(clock.Sleep(ctx, time.Second), context.Canceled)
+		require.Equal(time.Unix(3, 0), clock.Now(), "a cancelled sleep must not advance the clock")
+	})
+}
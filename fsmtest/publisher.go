@@ -0,0 +1,43 @@
+package fsmtest
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/tobbstr/fsm"
+)
+
+// Publisher is an in-memory fsm.Publisher that records published messages, for testing outbox flows.
+type Publisher struct {
+	mu        sync.Mutex
+	published []fsm.Message
+	err       error
+}
+
+var _ fsm.Publisher = (*Publisher)(nil)
+
+// Publish records the messages, or returns the error set with FailWith without recording them.
+func (p *Publisher) Publish(_ context.Context, messages []fsm.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, messages...)
+	return nil
+}
+
+// FailWith makes Publish return err until FailWith(nil) is called.
+func (p *Publisher) FailWith(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+// Published returns the messages published so far, in order.
+func (p *Publisher) Published() []fsm.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.published)
+}
@@ -0,0 +1,22 @@
+package fsmtest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tobbstr/fsm"
+)
+
+func TestPublisher(t *testing.T) {
+	require := require.New(t)
+
+	p := &Publisher{}
+	failure := errors.New("broker down")
+	p.FailWith(failure)
+	require.ErrorIs(p.Publish(t.Context(), []fsm.Message{{Topic: "lost"}}), failure)
+	p.FailWith(nil)
+	require.NoError(p.Publish(t.Context(), []fsm.Message{{Topic: "orders", Body: []byte("paid")}}))
+
+	require.Equal([]fsm.Message{{Topic: "orders", Body: []byte("paid")}}, p.Published())
+}
@@ -0,0 +1,179 @@
+package fsmtest
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tobbstr/fsm"
+)
+
+// RunStoreTests checks that a fsm.Store implementation meets the Store contract: missing machines, versioning,
+// optimistic locking under concurrent saves and creates, isolation between IDs and snapshot round-trips. newStore must
+// return an empty store; id must return distinct IDs for distinct n. Run it from a test of the implementation:
+//
+//	func TestRedisStore(t *testing.T) {
+//		fsmtest.RunStoreTests(t, func(t *testing.T) fsm.Store[string, State, Trigger] { return newRedisStore(t) },
+//			func(n int) string { return fmt.Sprint("machine-", n) })
+//	}
+func RunStoreTests[ID comparable, S, T ~uint](
+	t *testing.T, newStore func(t *testing.T) fsm.Store[ID, S, T], id func(n int) ID,
+) {
+	t.Helper()
+
+	t.Run("loading a missing machine returns ErrMachineNotFound", func(t *testing.T) {
+		store := newStore(t)
+		if _, err := store.Load(t.Context(), id(1)); !errors.Is(err, fsm.ErrMachineNotFound) {
+			t.Fatalf("Load of a missing machine: got error %v, want ErrMachineNotFound", err)
+		}
+	})
+
+	t.Run("saving with version 0 creates the machine at version 1", func(t *testing.T) {
+		store := newStore(t)
+		mustSave(t, store, id(1), fsm.Snapshot[S, T]{State: 1}, 0)
+		mustLoad(t, store, id(1), fsm.Snapshot[S, T]{State: 1}, 1)
+	})
+
+	t.Run("saving with the current version increments it", func(t *testing.T) {
+		store := newStore(t)
+		mustSave(t, store, id(1), fsm.Snapshot[S, T]{State: 1}, 0)
+		mustSave(t, store, id(1), fsm.Snapshot[S, T]{State: 2}, 1)
+		mustSave(t, store, id(1), fsm.Snapshot[S, T]{State: 3}, 2)
+		mustLoad(t, store, id(1), fsm.Snapshot[S, T]{State: 3}, 3)
+	})
+
+	t.Run("saving with a stale version conflicts and changes nothing", func(t *testing.T) {
+		store := newStore(t)
+		mustSave(t, store, id(1), fsm.Snapshot[S, T]{State: 1}, 0)
+		mustSave(t, store, id(1), fsm.Snapshot[S, T]{State: 2}, 1)
+		for _, expected := range []uint64{0, 1, 3} {
+			err := store.Save(t.Context(), id(1), fsm.Snapshot[S, T]{State: 9}, expected)
+			if !errors.Is(err, fsm.ErrVersionConflict) {
+				t.Fatalf("Save with expected version %d of a machine at version 2: got error %v, want ErrVersionConflict",
+					expected, err)
+			}
+		}
+		mustLoad(t, store, id(1), fsm.Snapshot[S, T]{State: 2}, 2)
+	})
+
+	t.Run("saving a missing machine with a non-zero version conflicts", func(t *testing.T) {
+		store := newStore(t)
+		if err := store.Save(t.Context(), id(1), fsm.Snapshot[S, T]{State: 1}, 1); !errors.Is(err, fsm.ErrVersionConflict) {
+			t.Fatalf("Save with expected version 1 of a missing machine: got error %v, want ErrVersionConflict", err)
+		}
+		if _, err := store.Load(t.Context(), id(1)); !errors.Is(err, fsm.ErrMachineNotFound) {
+			t.Fatalf("Load after a conflicting Save: got error %v, want ErrMachineNotFound", err)
+		}
+	})
+
+	t.Run("machines are isolated by ID", func(t *testing.T) {
+		store := newStore(t)
+		mustSave(t, store, id(1), fsm.Snapshot[S, T]{State: 1}, 0)
+		mustSave(t, store, id(2), fsm.Snapshot[S, T]{State: 2}, 0)
+		mustSave(t, store, id(2), fsm.Snapshot[S, T]{State: 3}, 1)
+		mustLoad(t, store, id(1), fsm.Snapshot[S, T]{State: 1}, 1)
+		mustLoad(t, store, id(2), fsm.Snapshot[S, T]{State: 3}, 2)
+	})
+
+	t.Run("snapshots round-trip with their deadlines and entry times", func(t *testing.T) {
+		store := newStore(t)
+		snapshot := fsm.Snapshot[S, T]{
+			State:     1,
+			Deadlines: []fsm.Deadline[S, T]{{State: 1, Trigger: 2, At: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}},
+			Seq:       7,
+			Entered:   []fsm.StateEntry[S]{{State: 1, At: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}},
+		}
+		mustSave(t, store, id(1), snapshot, 0)
+		mustLoad(t, store, id(1), snapshot, 1)
+	})
+
+	t.Run("exactly one concurrent save of the same version succeeds", func(t *testing.T) {
+		store := newStore(t)
+		mustSave(t, store, id(1), fsm.Snapshot[S, T]{State: 1}, 0)
+		mustSaveOnce(t, store, id(1), 1)
+	})
+
+	t.Run("exactly one concurrent create succeeds", func(t *testing.T) {
+		store := newStore(t)
+		mustSaveOnce(t, store, id(1), 0)
+	})
+}
+
+// mustSaveOnce saves the machine concurrently from several goroutines with the expected version, and checks that
+// exactly one succeeds and the others conflict.
+func mustSaveOnce[ID comparable, S, T ~uint](t *testing.T, store fsm.Store[ID, S, T], id ID, expected uint64) {
+	t.Helper()
+	const writers = 8
+	var wg sync.WaitGroup
+	errs := make([]error, writers)
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = store.Save(t.Context(), id, fsm.Snapshot[S, T]{State: S(i + 2)}, expected)
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, fsm.ErrVersionConflict):
+			t.Fatalf("concurrent Save: got error %v, want nil or ErrVersionConflict", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("concurrent Saves with expected version %d: %d succeeded, want 1", expected, succeeded)
+	}
+	record, err := store.Load(t.Context(), id)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if record.Version != expected+1 {
+		t.Fatalf("Load after concurrent Saves: got version %d, want %d", record.Version, expected+1)
+	}
+}
+
+func mustSave[ID comparable, S, T ~uint](t *testing.T, store fsm.Store[ID, S, T], id ID, snapshot fsm.Snapshot[S, T], expected uint64) {
+	t.Helper()
+	if err := store.Save(t.Context(), id, snapshot, expected); err != nil {
+		t.Fatalf("Save of machine %v with expected version %d: %v", id, expected, err)
+	}
+}
+
+func mustLoad[ID comparable, S, T ~uint](t *testing.T, store fsm.Store[ID, S, T], id ID, want fsm.Snapshot[S, T], version uint64) {
+	t.Helper()
+	record, err := store.Load(t.Context(), id)
+	if err != nil {
+		t.Fatalf("Load of machine %v: %v", id, err)
+	}
+	if record.Version != version {
+		t.Fatalf("Load of machine %v: got version %d, want %d", id, record.Version, version)
+	}
+	if !equalSnapshots(record.Snapshot, want) {
+		t.Fatalf("Load of machine %v: got snapshot %+v, want %+v", id, record.Snapshot, want)
+	}
+}
+
+// equalSnapshots compares times with time.Time.Equal, since stores need not preserve locations.
+func equalSnapshots[S, T ~uint](a, b fsm.Snapshot[S, T]) bool {
+	if a.State != b.State || a.Seq != b.Seq || len(a.Deadlines) != len(b.Deadlines) || len(a.Entered) != len(b.Entered) {
+		return false
+	}
+	for i := range a.Deadlines {
+		da, db := a.Deadlines[i], b.Deadlines[i]
+		if da.State != db.State || da.Trigger != db.Trigger || !da.At.Equal(db.At) {
+			return false
+		}
+	}
+	for i := range a.Entered {
+		ea, eb := a.Entered[i], b.Entered[i]
+		if ea.State != eb.State || !ea.At.Equal(eb.At) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// DefaultLogLevels are the levels a SlogObserver logs each FireResult at, unless changed with WithLevel.
+var DefaultLogLevels = map[FireResult]slog.Level{
+	Transitioned: slog.LevelInfo,
+	Ignored:      slog.LevelDebug,
+	Rejected:     slog.LevelWarn,
+	Forbidden:    slog.LevelWarn,
+	NotFound:     slog.LevelWarn,
+	Failed:       slog.LevelError,
+}
+
+// SlogObserver logs a structured record for every Fire call — transitions as well as rejections and failures — to a
+// slog.Logger, at a level chosen per FireResult. Attach it to a Builder or a Machine with Observe(logger.Observer()).
+type SlogObserver[S, T ~uint, Payload any] struct {
+	logger *slog.Logger
+	levels [Failed + 1]slog.Level
+	attrs  func(ctx context.Context, payload Payload) []slog.Attr
+}
+
+// NewSlogObserver creates a SlogObserver logging to logger at DefaultLogLevels.
+func NewSlogObserver[S, T ~uint, Payload any](logger *slog.Logger) *SlogObserver[S, T, Payload] {
+	o := &SlogObserver[S, T, Payload]{logger: logger}
+	for result, level := range DefaultLogLevels {
+		o.WithLevel(result, level)
+	}
+	return o
+}
+
+// WithLevel logs fires with the given result at level. To silence a result, use a level below the handler's minimum.
+// Results other than the declared FireResult constants are ignored.
+func (o *SlogObserver[S, T, Payload]) WithLevel(result FireResult, level slog.Level) *SlogObserver[S, T, Payload] {
+	if int(result) < len(o.levels) {
+		o.levels[result] = level
+	}
+	return o
+}
+
+// WithAttrs adds the attributes attrs returns to every record, e.g. an order ID taken from the payload. Log only what
+// may be retained.
+func (o *SlogObserver[S, T, Payload]) WithAttrs(attrs func(ctx context.Context, payload Payload) []slog.Attr) *SlogObserver[S, T, Payload] {
+	o.attrs = attrs
+	return o
+}
+
+// Observer returns the observer that feeds the logger.
+func (o *SlogObserver[S, T, Payload]) Observer() Observer[S, T, Payload] {
+	return Observer[S, T, Payload]{OnFire: o.log}
+}
+
+// level returns the level result is logged at, or slog.LevelInfo for an undeclared result.
+func (o *SlogObserver[S, T, Payload]) level(result FireResult) slog.Level {
+	if int(result) < len(o.levels) {
+		return o.levels[result]
+	}
+	return slog.LevelInfo
+}
+
+func (o *SlogObserver[S, T, Payload]) log(ctx context.Context, event FireEvent[S, T, Payload]) {
+	result := event.Result()
+	level := o.level(result)
+	if !o.logger.Enabled(ctx, level) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("trigger", fmt.Sprint(event.Trigger)),
+		slog.String("from", fmt.Sprint(event.From)),
+		slog.String("to", fmt.Sprint(event.To)),
+		slog.String("result", result.String()),
+		slog.Duration("duration", event.Duration),
+	}
+	if event.Transitioned && event.ResolvedFrom != event.From {
+		attrs = append(attrs, slog.String("resolved_from", fmt.Sprint(event.ResolvedFrom)))
+	}
+	if event.Err != nil {
+		attrs = append(attrs, slog.String("error", event.Err.Error()))
+	}
+	if o.attrs != nil {
+		attrs = append(attrs, o.attrs(ctx, event.Payload)...)
+	}
+	o.logger.LogAttrs(ctx, level, "fsm "+result.String(), attrs...)
+}
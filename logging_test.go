@@ -0,0 +1,85 @@
+package fsm
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newLoggedSpec(logger *SlogObserver[state, trigger, payload]) *Spec[state, trigger, payload] {
+	builder := NewBuilder[state, trigger, payload]().
+		WithClock(&stepClock{now: time.Unix(0, 0).UTC(), step: time.Second}).
+		Observe(logger.Observer())
+	builder.From(locked).On(unlock).To(unlocked)
+	builder.From(unlocked).On(lock).To(locked).When("door closed", func(payload) bool { return false })
+	return builder.Build()
+}
+
+func newTextLogger(buf *bytes.Buffer, level slog.Level) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+func TestSlogObserver(t *testing.T) {
+	t.Run("logs transitions and rejections at their default levels", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		var buf bytes.Buffer
+		observer := NewSlogObserver[state, trigger, payload](newTextLogger(&buf, slog.LevelInfo))
+		machine := New(newLoggedSpec(observer), locked)
+
+		/* ---------------------------------- When ---------------------------------- */
+		require.NoError(machine.Fire(t.Context(), unlock, payload{}))
+		err := machine.Fire(t.Context(), lock, payload{})
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.ErrorIs(err, ErrTransitionRejected)
+		require.Equal([]string{
+			`level=INFO msg="fsm transitioned" trigger=unlock from=locked to=unlocked result=transitioned duration=1s`,
+			`level=WARN msg="fsm rejected" trigger=lock from=unlocked to=unlocked result=rejected duration=1s ` +
+				`error=` + strconv.Quote(err.Error()),
+		}, strings.Split(strings.TrimSpace(buf.String()), "\n"))
+	})
+
+	t.Run("uses the configured level per result and adds attributes", func(t *testing.T) {
+		require := require.New(t)
+
+		var buf bytes.Buffer
+		observer := NewSlogObserver[state, trigger, payload](newTextLogger(&buf, slog.LevelInfo)).
+			WithLevel(Transitioned, slog.LevelDebug).
+			WithLevel(Rejected, slog.LevelError).
+			WithAttrs(func(context.Context, payload) []slog.Attr { return []slog.Attr{slog.String("door", "front")} })
+		machine := New(newLoggedSpec(observer), locked)
+
+		require.NoError(machine.Fire(t.Context(), unlock, payload{}))
+		err := machine.Fire(t.Context(), lock, payload{})
+
+		require.Error(err)
+		require.Equal(`level=ERROR msg="fsm rejected" trigger=lock from=unlocked to=unlocked result=rejected duration=1s `+
+			`error=`+strconv.Quote(err.Error())+` door=front`, strings.TrimSpace(buf.String()))
+	})
+
+	t.Run("ignores results outside the declared range", func(t *testing.T) {
+		require := require.New(t)
+
+		observer := NewSlogObserver[state, trigger, payload](slog.New(slog.DiscardHandler))
+
+		require.NotPanics(func() { observer.WithLevel(FireResult(42), slog.LevelError) })
+		require.Equal(slog.LevelInfo, observer.level(FireResult(42)))
+		require.Equal(slog.LevelError, observer.level(Failed))
+	})
+}
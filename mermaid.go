@@ -0,0 +1,113 @@
+package fsm
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultActiveStyle is the style Machine.MermaidJSDiagram gives the active hierarchy, unless
+// DiagramOptions.ActiveStyle is set.
+const DefaultActiveStyle = "fill:#ffe08a,stroke:#c49000,stroke-width:2px"
+
+// MermaidJSDiagram returns a state diagram in Mermaid.js syntax for the FSM Spec. Composite states are rendered as
+// nested `state X { }` blocks with `[*] -->` marking the initial substate, described hooks as notes, and each branch
+// as a `trigger [guard] / action` edge. At most one DiagramOptions may be passed.
+func (spec *Spec[S, T, Payload]) MermaidJSDiagram(opts ...DiagramOptions) string {
+	return spec.mermaid(diagramOptions(opts), nil)
+}
+
+// MermaidJSDiagram returns the spec's Mermaid.js state diagram with the machine's active hierarchy highlighted. At
+// most one DiagramOptions may be passed.
+func (m *Machine[S, T, Payload]) MermaidJSDiagram(opts ...DiagramOptions) string {
+	return m.spec.mermaid(diagramOptions(opts), m.ActiveHierarchy())
+}
+
+// mermaid renders the diagram, styling the active states, if any, with the active style.
+func (spec *Spec[S, T, Payload]) mermaid(o DiagramOptions, active []S) string {
+	d := spec.diagram(o)
+	var sb strings.Builder
+	sb.WriteString("stateDiagram-v2\n")
+	if o.Direction != "" {
+		sb.WriteString("direction " + string(o.Direction) + "\n")
+	}
+	var described []S // states with hook notes, in diagram order
+	var walk func(state S, depth int, nested bool)
+	walk = func(state S, depth int, nested bool) {
+		if len(d.hookLines(state)) > 0 {
+			described = append(described, state)
+		}
+		if !d.isComposite(state) {
+			// Nested states must be declared inside their parent's block; top-level ones are declared by their edges.
+			if nested || !d.hasEdge(state) {
+				sb.WriteString(indent(depth) + fmtName(state) + "\n")
+			}
+			return
+		}
+		sb.WriteString(indent(depth) + "state " + fmtName(state) + " {\n")
+		if initial, ok := d.initial[state]; ok {
+			sb.WriteString(indent(depth+1) + "[*] --> " + fmtName(initial) + "\n")
+		}
+		for _, child := range d.children[state] {
+			walk(child, depth+1, true)
+		}
+		sb.WriteString(indent(depth) + "}\n")
+	}
+	for _, st := range d.roots {
+		walk(st, 0, false)
+	}
+	for _, e := range d.edges {
+		sb.WriteString(fmtName(e.from) + " --> " + fmtName(e.to) + " : " + e.label + "\n")
+	}
+	for _, st := range described {
+		sb.WriteString("note right of " + fmtName(st) + "\n")
+		for _, line := range d.hookLines(st) {
+			sb.WriteString(indent(1) + line + "\n")
+		}
+		sb.WriteString("end note\n")
+	}
+	writeMermaidStyles(&sb, d, o, active)
+	return sb.String()
+}
+
+// writeMermaidStyles writes a classDef per distinct style in o.Styles, in diagram order, and one for the active
+// states that the diagram draws.
+func writeMermaidStyles[S ~uint](sb *strings.Builder, d *diagram[S], o DiagramOptions, active []S) {
+	var styles []string     // distinct styles, in first-use order
+	classes := [][]string{} // state names per style
+	d.walk(func(state S) {
+		style, ok := o.Styles[fmtName(state)]
+		if !ok {
+			return
+		}
+		i := slices.Index(styles, style)
+		if i < 0 {
+			i = len(styles)
+			styles = append(styles, style)
+			classes = append(classes, nil)
+		}
+		classes[i] = append(classes[i], fmtName(state))
+	})
+	for i, style := range styles {
+		class := "style" + strconv.Itoa(i)
+		sb.WriteString("classDef " + class + " " + style + "\n")
+		sb.WriteString("class " + strings.Join(classes[i], ",") + " " + class + "\n")
+	}
+	// States hidden by the options are skipped: a collapsed state's active substates are drawn as the collapsed state,
+	// which is itself active.
+	var names []string
+	for i := len(active) - 1; i >= 0; i-- { // outermost first
+		if d.drawn(active[i]) {
+			names = append(names, fmtName(active[i]))
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	style := o.ActiveStyle
+	if style == "" {
+		style = DefaultActiveStyle
+	}
+	sb.WriteString("classDef active " + style + "\n")
+	sb.WriteString("class " + strings.Join(names, ",") + " active\n")
+}
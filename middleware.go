@@ -0,0 +1,67 @@
+package fsm
+
+// Phase identifies where in a transition an action or hook runs.
+type Phase uint8
+
+const (
+	PhaseGuard  Phase = iota + 1 // a branch condition (When); only reported by PanicError, never wrapped by Middleware
+	PhaseExit                    // an OnExit state hook
+	PhaseAction                  // a transition action (Do)
+	PhaseEntry                   // an OnEntry state hook
+)
+
+// String returns the phase name.
+func (p Phase) String() string {
+	switch p {
+	case PhaseGuard:
+		return "guard"
+	case PhaseExit:
+		return "exit"
+	case PhaseAction:
+		return "action"
+	case PhaseEntry:
+		return "entry"
+	default:
+		return "unknown"
+	}
+}
+
+// Step describes the action or hook a Middleware wraps.
+//
+// For PhaseAction, From, To and Trigger identify the branch and State equals From. For PhaseExit and PhaseEntry,
+// State is the state owning the hook; hooks are shared by every transition that exits or enters the state, so From,
+// To and Trigger are zero.
+type Step[S, T ~uint] struct {
+	Phase       Phase
+	State       S
+	From        S
+	To          S
+	Trigger     T
+	Description string // the Do, OnEntry or OnExit description
+}
+
+// Middleware wraps an action or hook with cross-cutting behavior such as timing, tracing or transaction handling. It
+// is called once per action and hook at Build time, never by Fire, and returns the action to run in place of next.
+type Middleware[S, T ~uint, Payload any] func(step Step[S, T], next Action[Payload]) Action[Payload]
+
+// Use appends middleware applied to every transition action and state hook at Build time. The first middleware added
+// is the outermost. Without middleware, actions and hooks are used as given, keeping Fire free of any overhead.
+func (b *Builder[S, T, Payload]) Use(middleware ...Middleware[S, T, Payload]) *Builder[S, T, Payload] {
+	b.middleware = append(b.middleware, middleware...)
+	return b
+}
+
+// wrap applies the Builder's middleware to action and, with RecoverPanics, panic recovery around the whole chain. A
+// nil action is returned unchanged.
+func (b *Builder[S, T, Payload]) wrap(step Step[S, T], action Action[Payload]) Action[Payload] {
+	if action == nil {
+		return nil
+	}
+	for i := len(b.middleware) - 1; i >= 0; i-- {
+		action = b.middleware[i](step, action)
+	}
+	if b.recoverPanics {
+		action = recoverAction(step, action)
+	}
+	return action
+}
@@ -0,0 +1,88 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder_Use(t *testing.T) {
+	t.Run("wraps actions and hooks with step metadata, first middleware outermost", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		var calls []string
+		record := func(name string) Action[payload] {
+			return func(context.Context, payload) error {
+				calls = append(calls, name)
+				return nil
+			}
+		}
+		tag := func(name string) Middleware[state, trigger, payload] {
+			return func(step Step[state, trigger], next Action[payload]) Action[payload] {
+				return func(ctx context.Context, p payload) error {
+					calls = append(calls, fmt.Sprintf("%s>%v:%v:%s", name, step.Phase, step.State, step.Description))
+					err := next(ctx, p)
+					calls = append(calls, name+"<")
+					return err
+				}
+			}
+		}
+		builder := NewBuilder[state, trigger, payload]()
+		builder.Use(tag("outer"), tag("inner"))
+		builder.From(locked).On(unlock).To(unlocked).Do("open", record("action"))
+		builder.From(locked).OnExit("leave", record("exit"))
+		builder.From(unlocked).OnEntry("arrive", record("entry"))
+
+		/* ---------------------------------- When ---------------------------------- */
+		err := New(builder.Build(), locked).Fire(t.Context(), unlock, payload{})
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.NoError(err)
+		require.Equal([]string{
+			"outer>exit:locked:leave", "inner>exit:locked:leave", "exit", "inner<", "outer<",
+			"outer>action:locked:open", "inner>action:locked:open", "action", "inner<", "outer<",
+			"outer>entry:unlocked:arrive", "inner>entry:unlocked:arrive", "entry", "inner<", "outer<",
+		}, calls)
+	})
+
+	t.Run("action steps carry the transition", func(t *testing.T) {
+		require := require.New(t)
+
+		var got Step[state, trigger]
+		builder := NewBuilder[state, trigger, payload]()
+		builder.Use(func(step Step[state, trigger], next Action[payload]) Action[payload] {
+			got = step
+			return next
+		})
+		builder.From(locked).On(unlock).To(unlocked).Do("open", func(context.Context, payload) error { return nil })
+		builder.Build()
+
+		require.Equal(Step[state, trigger]{
+			Phase:       PhaseAction,
+			State:       locked,
+			From:        locked,
+			To:          unlocked,
+			Trigger:     unlock,
+			Description: "open",
+		}, got)
+	})
+
+	t.Run("branches without an action are not wrapped", func(t *testing.T) {
+		require := require.New(t)
+
+		wrapped := 0
+		builder := NewBuilder[state, trigger, payload]()
+		builder.Use(func(step Step[state, trigger], next Action[payload]) Action[payload] {
+			wrapped++
+			return next
+		})
+		builder.From(locked).On(unlock).To(unlocked)
+
+		machine := New(builder.Build(), locked)
+		require.NoError(machine.Fire(t.Context(), unlock, payload{}))
+		require.Zero(wrapped)
+	})
+}
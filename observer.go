@@ -0,0 +1,193 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Observer receives events from machines created from a spec. Every field is optional; nil fields are skipped. It is
+// a struct of functions, like StateHooks, so new events can be added without breaking existing observers.
+type Observer[S, T ~uint, Payload any] struct {
+	// OnAttempt is called after every attempt of an action or hook that has a Retry policy. Attempts are reported from
+	// the Build-time action wrappers, so it must be registered with Builder.Observe; Machine.Observe panics on it.
+	OnAttempt func(ctx context.Context, event AttemptEvent[S, T])
+	// OnTransition is called by Fire after every successful transition, once the machine is in its new state.
+	OnTransition func(ctx context.Context, event TransitionEvent[S, T, Payload])
+	// OnFire is called once at the end of every Fire, whether it transitioned, was ignored or failed. Registering it
+	// makes Fire read the Clock twice and allocate, to measure and describe the call.
+	OnFire func(ctx context.Context, event FireEvent[S, T, Payload])
+}
+
+// FireEvent describes a completed call to Fire.
+type FireEvent[S, T ~uint, Payload any] struct {
+	From         S             // the state before Fire
+	To           S             // the state after Fire; equal to From unless it transitioned
+	Trigger      T             // the trigger fired
+	Payload      Payload       // the payload fired with
+	Transitioned bool          // whether a transition completed; false if Fire failed or returned nil without one
+	ResolvedFrom S             // the hierarchy level whose branch was selected; valid iff Transitioned
+	Err          error         // the error Fire returned
+	At           time.Time     // when Fire was called, on the spec's Clock
+	Duration     time.Duration // how long Fire took, on the spec's Clock
+}
+
+// FireResult classifies the outcome of a Fire call.
+type FireResult uint8
+
+const (
+	// Transitioned means a transition completed.
+	Transitioned FireResult = iota
+	// Ignored means Fire returned nil without transitioning: the trigger was ignored, or handled by an
+	// UnhandledHandler that returned nil.
+	Ignored
+	// Rejected means no branch's condition matched; the error wraps ErrTransitionRejected.
+	Rejected
+	// Forbidden means the trigger was forbidden; the error wraps ErrTransitionForbidden.
+	Forbidden
+	// NotFound means no transition was declared; the error wraps ErrNotFound.
+	NotFound
+	// Failed means a guard, action or hook failed, or the context was done.
+	Failed
+)
+
+// String returns the result's snake_case name, suitable as a log attribute or metric label.
+func (r FireResult) String() string {
+	switch r {
+	case Transitioned:
+		return "transitioned"
+	case Ignored:
+		return "ignored"
+	case Rejected:
+		return "rejected"
+	case Forbidden:
+		return "forbidden"
+	case NotFound:
+		return "not_found"
+	case Failed:
+		return "failed"
+	default:
+		return fmt.Sprintf("FireResult(%d)", uint8(r))
+	}
+}
+
+// Result classifies the event.
+func (e FireEvent[S, T, Payload]) Result() FireResult {
+	switch {
+	case e.Transitioned:
+		return Transitioned
+	case e.Err == nil:
+		return Ignored
+	case errors.Is(e.Err, ErrTransitionRejected):
+		return Rejected
+	case errors.Is(e.Err, ErrTransitionForbidden):
+		return Forbidden
+	case errors.Is(e.Err, ErrNotFound):
+		return NotFound
+	default:
+		return Failed
+	}
+}
+
+// TransitionEvent describes a completed transition.
+type TransitionEvent[S, T ~uint, Payload any] struct {
+	From         S       // the state before Fire
+	To           S       // the state after Fire, including any initial substate
+	Trigger      T       // the trigger fired
+	Payload      Payload // the payload fired with
+	ResolvedFrom S       // the hierarchy level whose branch was selected
+	Exited       []S     // the states exited, innermost first
+	Entered      []S     // the states entered, outermost first
+	// Dwell is the time spent in each Exited state, on the spec's Clock; nil unless the machine tracks time in state.
+	Dwell []time.Duration
+}
+
+// AttemptEvent describes one attempt of a retried action or hook.
+type AttemptEvent[S, T ~uint] struct {
+	Step    Step[S, T]    // the action or hook being attempted
+	Attempt int           // 1-based attempt number
+	Err     error         // the attempt's error; nil if it succeeded
+	Backoff time.Duration // the delay before the next attempt; zero if there is none
+}
+
+// Observe adds an observer to the spec. It receives events from every machine created from the spec.
+func (b *Builder[S, T, Payload]) Observe(observer Observer[S, T, Payload]) *Builder[S, T, Payload] {
+	b.observers = append(b.observers, observer)
+	return b
+}
+
+// Observe adds an observer to the machine only. Spec-level observers are notified first. It panics if the observer
+// sets OnAttempt, which only observers registered with Builder.Observe receive.
+func (m *Machine[S, T, Payload]) Observe(observer Observer[S, T, Payload]) *Machine[S, T, Payload] {
+	if observer.OnAttempt != nil {
+		panic("OnAttempt observers must be registered with Builder.Observe: attempts are reported by the spec's actions")
+	}
+	m.observers = append(m.observers, observer)
+	m.observesFire = m.observesFire || observesFire(observer)
+	return m
+}
+
+func observesFire[S, T ~uint, Payload any](o Observer[S, T, Payload]) bool {
+	return o.OnFire != nil
+}
+
+// fireObserved is Fire for machines with OnFire observers: it measures the call and reports it.
+func (m *Machine[S, T, Payload]) fireObserved(ctx context.Context, trigger T, payload Payload) error {
+	clock := m.spec.clock
+	event := &FireEvent[S, T, Payload]{From: m.state, Trigger: trigger, Payload: payload, At: clock.Now()}
+	m.firing = event
+	defer func() { m.firing = nil }() // also when a panic escapes, so a later Fire does not update a stale event
+	err := m.fireOnce(ctx, trigger, payload)
+	event.To, event.Err, event.Duration = m.state, err, clock.Now().Sub(event.At)
+	for _, o := range m.spec.observers {
+		if o.OnFire != nil {
+			o.OnFire(ctx, *event)
+		}
+	}
+	for _, o := range m.observers {
+		if o.OnFire != nil {
+			o.OnFire(ctx, *event)
+		}
+	}
+	return err
+}
+
+// notifyTransition completes the event with the exited and entered states and delivers it. It allocates, so Fire only
+// calls it when observers are registered. exited is innermost first; entered is outermost last, as returned by
+// transitionPath, and initial is the initial substate entered, if any. now is the transition time when the machine
+// tracks time in state.
+func (m *Machine[S, T, Payload]) notifyTransition(
+	ctx context.Context, event TransitionEvent[S, T, Payload], exited, entered []S, initial *S, now time.Time,
+) {
+	event.Exited = slices.Clone(exited)
+	if m.entered != nil {
+		event.Dwell = make([]time.Duration, len(exited))
+		for i, st := range exited {
+			if uint(st) < uint(len(m.entered)) {
+				event.Dwell[i] = now.Sub(m.entered[st])
+			}
+		}
+	}
+	event.Entered = make([]S, 0, len(entered)+1)
+	for i := len(entered) - 1; i >= 0; i-- {
+		event.Entered = append(event.Entered, entered[i])
+	}
+	if initial != nil {
+		event.Entered = append(event.Entered, *initial)
+	}
+	if m.firing != nil {
+		m.firing.Transitioned, m.firing.ResolvedFrom = true, event.ResolvedFrom
+	}
+	for _, o := range m.spec.observers {
+		if o.OnTransition != nil {
+			o.OnTransition(ctx, event)
+		}
+	}
+	for _, o := range m.observers {
+		if o.OnTransition != nil {
+			o.OnTransition(ctx, event)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package fsm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserver_OnTransition(t *testing.T) {
+	require := require.New(t)
+
+	/* ---------------------------------- Given --------------------------------- */
+	var specEvents, machineEvents []TransitionEvent[state, trigger, payload]
+	builder := NewBuilder[state, trigger, payload]().Observe(Observer[state, trigger, payload]{
+		OnTransition: func(ctx context.Context, e TransitionEvent[state, trigger, payload]) {
+			specEvents = append(specEvents, e)
+		},
+	})
+	builder.From(grandchild).WithParent(child)
+	builder.From(child).WithParent(root)
+	builder.From(unlocked).WithParent(root).WithInitial(locked)
+	builder.From(locked).WithParent(unlocked)
+	builder.From(child).On(unlock).To(unlocked)
+	builder.From(locked).On(lock).To(locked).When("never", func(payload) bool { return false })
+	machine := New(builder.Build(), grandchild).Observe(Observer[state, trigger, payload]{
+		OnTransition: func(ctx context.Context, e TransitionEvent[state, trigger, payload]) {
+			machineEvents = append(machineEvents, e)
+		},
+	})
+
+	/* ---------------------------------- When ---------------------------------- */
+	require.NoError(machine.Fire(t.Context(), unlock, payload{}))
+	require.Error(machine.Fire(t.Context(), lock, payload{}))
+
+	/* ---------------------------------- Then ---------------------------------- */
+	want := []TransitionEvent[state, trigger, payload]{{
+		From:         grandchild,
+		To:           locked,
+		Trigger:      unlock,
+		ResolvedFrom: child,
+		Exited:       []state{grandchild, child},
+		Entered:      []state{unlocked, locked},
+	}}
+	require.Equal(want, specEvents, "failed transitions must not be reported")
+	require.Equal(want, machineEvents)
+}
+
+// stepClock is a Clock whose Now advances by step on every call.
+type stepClock struct {
+	SystemClock
+	now  time.Time
+	step time.Duration
+}
+
+func (c *stepClock) Now() time.Time {
+	now := c.now
+	c.now = c.now.Add(c.step)
+	return now
+}
+
+func TestObserver_OnFire(t *testing.T) {
+	require := require.New(t)
+
+	/* ---------------------------------- Given --------------------------------- */
+	var events []FireEvent[state, trigger, payload]
+	clock := &stepClock{now: time.Unix(0, 0), step: time.Millisecond}
+	builder := NewBuilder[state, trigger, payload]().WithClock(clock)
+	builder.From(locked).On(unlock).To(unlocked)
+	builder.From(unlocked).On(lock).To(locked).When("never", func(payload) bool { return false })
+	builder.From(unlocked).Ignore(unlock)
+	machine := New(builder.Build(), locked).Observe(Observer[state, trigger, payload]{
+		OnFire: func(ctx context.Context, e FireEvent[state, trigger, payload]) {
+			events = append(events, e)
+		},
+	})
+
+	/* ---------------------------------- When ---------------------------------- */
+	require.NoError(machine.Fire(t.Context(), unlock, payload{}))
+	require.NoError(machine.Fire(t.Context(), unlock, payload{}))
+	rejected := machine.Fire(t.Context(), lock, payload{})
+	notFound := machine.Fire(t.Context(), trigger(99), payload{})
+
+	/* ---------------------------------- Then ---------------------------------- */
+	require.Len(events, 4)
+	require.Equal(FireEvent[state, trigger, payload]{
+		From: locked, To: unlocked, Trigger: unlock, Transitioned: true, ResolvedFrom: locked,
+		At: time.Unix(0, 0), Duration: time.Millisecond,
+	}, events[0])
+	var results []string
+	for _, e := range events {
+		results = append(results, e.Result().String())
+	}
+	require.Equal([]string{"transitioned", "ignored", "rejected", "not_found"}, results)
+	require.Equal(rejected, events[2].Err)
+	require.Equal(notFound, events[3].Err)
+	require.Equal(unlocked, events[3].To)
+}
+
+func TestObserver_OnFire_PanicClearsEvent(t *testing.T) {
+	require := require.New(t)
+
+	/* ---------------------------------- Given --------------------------------- */
+	builder := NewBuilder[state, trigger, payload]()
+	builder.From(locked).On(unlock).To(unlocked).Do("jam", func(context.Context, payload) error { panic("jammed") })
+	machine := New(builder.Build(), locked).Observe(Observer[state, trigger, payload]{
+		OnFire: func(context.Context, FireEvent[state, trigger, payload]) {},
+	})
+
+	/* ---------------------------------- When ---------------------------------- */
+	require.Panics(func() { _ = machine.Fire(t.Context(), unlock, payload{}) })
+
+	/* ---------------------------------- Then ---------------------------------- */
+	require.Nil(machine.firing)
+}
+
+func TestMachine_Observe_RejectsOnAttempt(t *testing.T) {
+	machine := New(NewBuilder[state, trigger, payload]().Build(), locked)
+
+	require.PanicsWithValue(t,
+		"OnAttempt observers must be registered with Builder.Observe: attempts are reported by the spec's actions",
+		func() {
+			machine.Observe(Observer[state, trigger, payload]{OnAttempt: func(context.Context, AttemptEvent[state, trigger]) {}})
+		})
+}
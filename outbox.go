@@ -0,0 +1,260 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrNoOutbox is returned by Enqueue when the context carries no Outbox.
+var ErrNoOutbox = errors.New("no outbox in context")
+
+// Message is a domain event enqueued by an action or hook, to be published once the transition has been committed.
+type Message struct {
+	Topic string `json:"topic"`
+	Key   string `json:"key,omitempty"`
+	Body  []byte `json:"body"`
+}
+
+// Publisher publishes messages to a broker.
+type Publisher interface {
+	Publish(ctx context.Context, messages []Message) error
+}
+
+// Outbox buffers the messages enqueued by the actions and hooks of one or more Fire calls, so that they are published
+// only if the surrounding transaction commits. Create one per Fire (or per transaction) with WithOutbox. It is safe
+// for concurrent use: each Fire buffers its messages separately and adds them only once it succeeds.
+type Outbox struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+type outboxKey struct{}
+
+// WithOutbox returns a context carrying a new Outbox, and the Outbox. Pass the context to Fire so that actions and
+// hooks can Enqueue messages.
+func WithOutbox(ctx context.Context) (context.Context, *Outbox) {
+	o := &Outbox{}
+	return context.WithValue(ctx, outboxKey{}, o), o
+}
+
+// outboxFrom returns the Outbox carried by ctx, or nil.
+func outboxFrom(ctx context.Context) *Outbox {
+	o, _ := ctx.Value(outboxKey{}).(*Outbox)
+	return o
+}
+
+// Enqueue adds messages to the Outbox carried by ctx. Call it from actions and hooks instead of publishing directly.
+// It returns ErrNoOutbox if ctx carries no Outbox, so that a misconfigured call site fails the transition rather than
+// losing events. Messages enqueued by a failed attempt of an action with a Retry policy are discarded, as are all
+// messages enqueued by a Fire that returns an error.
+func Enqueue(ctx context.Context, messages ...Message) error {
+	o := outboxFrom(ctx)
+	if o == nil {
+		return ErrNoOutbox
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages = append(o.messages, messages...)
+	return nil
+}
+
+// Messages returns the buffered messages in enqueue order.
+func (o *Outbox) Messages() []Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return slices.Clone(o.messages)
+}
+
+// AfterCommit calls commit and, only if it succeeds, publishes the buffered messages and clears the buffer. If commit
+// fails the messages are discarded and its error returned. Publishing happens after the commit, so messages are lost
+// if the process dies in between or publishing fails; use WriteTo and a Relay when they must not be.
+func (o *Outbox) AfterCommit(ctx context.Context, commit func() error, publisher Publisher) error {
+	messages := o.take()
+	if err := commit(); err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	if err := publisher.Publish(ctx, messages); err != nil {
+		return fmt.Errorf("publishing %d outbox messages: %w", len(messages), err)
+	}
+	return nil
+}
+
+// WriteTo appends the buffered messages to store and clears the buffer. Bind store to the transaction the machine is
+// saved in, so the messages commit or roll back with the transition; a Relay then publishes them.
+func (o *Outbox) WriteTo(ctx context.Context, store OutboxStore) error {
+	messages := o.take()
+	if len(messages) == 0 {
+		return nil
+	}
+	if err := store.Append(ctx, messages); err != nil {
+		return fmt.Errorf("writing %d outbox messages: %w", len(messages), err)
+	}
+	return nil
+}
+
+func (o *Outbox) take() []Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	messages := o.messages
+	o.messages = nil
+	return messages
+}
+
+// scope returns a context carrying a new Outbox that buffers the messages enqueued through it apart from o, until
+// they are handed to o with commit. Fire calls sharing o concurrently thus never discard each other's messages.
+func (o *Outbox) scope(ctx context.Context) (context.Context, *Outbox) {
+	scoped := &Outbox{}
+	return context.WithValue(ctx, outboxKey{}, scoped), scoped
+}
+
+// commit appends the messages of scoped, an Outbox returned by scope, to o.
+func (o *Outbox) commit(scoped *Outbox) {
+	messages := scoped.take()
+	if len(messages) == 0 {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages = append(o.messages, messages...)
+}
+
+// fireBuffered is Fire for a ctx carrying an Outbox: it hands the messages enqueued during the Fire to outbox only if
+// the Fire succeeds, so the messages of a transition that did not happen are never published.
+func (m *Machine[S, T, Payload]) fireBuffered(ctx context.Context, outbox *Outbox, trigger T, payload Payload) error {
+	ctx, scoped := outbox.scope(ctx)
+	if err := m.fireUnbuffered(ctx, trigger, payload); err != nil {
+		return err
+	}
+	outbox.commit(scoped)
+	return nil
+}
+
+// OutboxEntry is a message stored in an OutboxStore, identified by a store-assigned, increasing ID.
+type OutboxEntry struct {
+	ID uint64 `json:"id"`
+	Message
+}
+
+// OutboxStore persists outbox messages until a Relay has published them. Implementations must be safe for concurrent
+// use.
+type OutboxStore interface {
+	// Append stores messages in order.
+	Append(ctx context.Context, messages []Message) error
+	// Pending returns up to limit unpublished entries, ordered by ID. A limit below 1 returns none.
+	Pending(ctx context.Context, limit int) ([]OutboxEntry, error)
+	// MarkPublished removes the entries with the IDs from the pending entries.
+	MarkPublished(ctx context.Context, ids []uint64) error
+}
+
+// MemoryOutboxStore is an in-memory OutboxStore, for tests and single-process use.
+type MemoryOutboxStore struct {
+	mu      sync.Mutex
+	nextID  uint64
+	entries []OutboxEntry
+}
+
+var _ OutboxStore = (*MemoryOutboxStore)(nil)
+
+// NewMemoryOutboxStore creates an empty MemoryOutboxStore.
+func NewMemoryOutboxStore() *MemoryOutboxStore {
+	return &MemoryOutboxStore{}
+}
+
+// Append stores the messages.
+func (s *MemoryOutboxStore) Append(_ context.Context, messages []Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range messages {
+		s.nextID++
+		s.entries = append(s.entries, OutboxEntry{ID: s.nextID, Message: m})
+	}
+	return nil
+}
+
+// Pending returns up to limit unpublished entries.
+func (s *MemoryOutboxStore) Pending(_ context.Context, limit int) ([]OutboxEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.entries[:min(max(limit, 0), len(s.entries))]), nil
+}
+
+// MarkPublished removes the entries with the IDs.
+func (s *MemoryOutboxStore) MarkPublished(_ context.Context, ids []uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = slices.DeleteFunc(s.entries, func(e OutboxEntry) bool { return slices.Contains(ids, e.ID) })
+	return nil
+}
+
+// Relay publishes the messages of an OutboxStore, in order, and marks them published. Delivery is at least once: a
+// batch is published again if marking it fails, so consumers must tolerate duplicates.
+type Relay struct {
+	store     OutboxStore
+	publisher Publisher
+	batchSize int
+	clock     Clock
+}
+
+// NewRelay creates a Relay draining store to publisher in batches of 100.
+func NewRelay(store OutboxStore, publisher Publisher) *Relay {
+	return &Relay{store: store, publisher: publisher, batchSize: 100, clock: SystemClock{}}
+}
+
+// WithBatchSize sets the maximum number of messages published at once. Sizes below 1 are raised to 1.
+func (r *Relay) WithBatchSize(n int) *Relay {
+	r.batchSize = max(n, 1)
+	return r
+}
+
+// WithClock sets the clock Run waits on. The default is SystemClock.
+func (r *Relay) WithClock(clock Clock) *Relay {
+	r.clock = clock
+	return r
+}
+
+// Drain publishes pending messages until none are left and returns how many it published. It stops at the first
+// error, leaving the failed batch pending.
+func (r *Relay) Drain(ctx context.Context) (int, error) {
+	published := 0
+	for {
+		entries, err := r.store.Pending(ctx, r.batchSize)
+		if err != nil {
+			return published, fmt.Errorf("loading pending outbox messages: %w", err)
+		}
+		if len(entries) == 0 {
+			return published, nil
+		}
+		messages := make([]Message, len(entries))
+		ids := make([]uint64, len(entries))
+		for i, e := range entries {
+			messages[i], ids[i] = e.Message, e.ID
+		}
+		if err := r.publisher.Publish(ctx, messages); err != nil {
+			return published, fmt.Errorf("publishing %d outbox messages: %w", len(messages), err)
+		}
+		if err := r.store.MarkPublished(ctx, ids); err != nil {
+			return published, fmt.Errorf("marking %d outbox messages published: %w", len(ids), err)
+		}
+		published += len(entries)
+	}
+}
+
+// Run drains every interval until ctx is done, then returns ctx's error. Drain errors are passed to onError, which may
+// be nil.
+func (r *Relay) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	for {
+		if _, err := r.Drain(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+		if err := r.clock.Sleep(ctx, interval); err != nil {
+			return err
+		}
+	}
+}
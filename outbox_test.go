@@ -0,0 +1,286 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tobbstr/fsm"
+	"github.com/tobbstr/fsm/fsmtest"
+)
+
+func newOutboxSpec(attemptsBeforeSuccess int) *fsm.Spec[paymentState, paymentTrigger, paymentPayload] {
+	attempts := 0
+	b := fsm.NewBuilder[paymentState, paymentTrigger, paymentPayload]().WithClock(fsmtest.NewClock(time.Unix(0, 0)))
+	b.From(awaitingPayment).On(pay).To(paid).
+		Do("charge card", func(ctx context.Context, _ paymentPayload) error {
+			attempts++
+			if err := fsm.Enqueue(ctx, fsm.Message{Topic: "charges", Body: []byte{byte(attempts)}}); err != nil {
+				return err
+			}
+			if attempts <= attemptsBeforeSuccess {
+				return errors.New("card declined")
+			}
+			return nil
+		}).
+		Retry(fsm.RetryPolicy{MaxAttempts: 3})
+	b.From(paid).OnEntry("announce payment", func(ctx context.Context, _ paymentPayload) error {
+		return fsm.Enqueue(ctx, fsm.Message{Topic: "payments", Key: "order-1", Body: []byte("paid")})
+	})
+	return b.Build()
+}
+
+func TestOutbox(t *testing.T) {
+	t.Run("publishes the enqueued messages only after the commit succeeds", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		publisher := &fsmtest.Publisher{}
+		machine := fsm.New(newOutboxSpec(0), awaitingPayment)
+		ctx, outbox := fsm.WithOutbox(t.Context())
+
+		/* ---------------------------------- When ---------------------------------- */
+		require.NoError(machine.Fire(ctx, pay, paymentPayload{}))
+		err := outbox.AfterCommit(ctx, func() error {
+			require.Empty(publisher.Published(), "nothing may be published before the commit")
+			return nil
+		}, publisher)
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.NoError(err)
+		require.Equal([]fsm.Message{
+			{Topic: "charges", Body: []byte{1}},
+			{Topic: "payments", Key: "order-1", Body: []byte("paid")},
+		}, publisher.Published())
+		require.Empty(outbox.Messages())
+	})
+
+	t.Run("discards the enqueued messages when the commit fails", func(t *testing.T) {
+		require := require.New(t)
+
+		publisher := &fsmtest.Publisher{}
+		machine := fsm.New(newOutboxSpec(0), awaitingPayment)
+		ctx, outbox := fsm.WithOutbox(t.Context())
+		require.NoError(machine.Fire(ctx, pay, paymentPayload{}))
+		commitErr := errors.New("serialization failure")
+
+		err := outbox.AfterCommit(ctx, func() error { return commitErr }, publisher)
+
+		require.ErrorIs(err, commitErr)
+		require.Empty(publisher.Published())
+		require.Empty(outbox.Messages())
+	})
+
+	t.Run("discards the messages of failed attempts", func(t *testing.T) {
+		require := require.New(t)
+
+		machine := fsm.New(newOutboxSpec(2), awaitingPayment)
+		ctx, outbox := fsm.WithOutbox(t.Context())
+
+		require.NoError(machine.Fire(ctx, pay, paymentPayload{}))
+
+		require.Equal([]fsm.Message{
+			{Topic: "charges", Body: []byte{3}},
+			{Topic: "payments", Key: "order-1", Body: []byte("paid")},
+		}, outbox.Messages())
+	})
+
+	t.Run("discards the messages of a Fire whose entry hook fails", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		hookErr := errors.New("stock service down")
+		b := fsm.NewBuilder[paymentState, paymentTrigger, paymentPayload]()
+		b.From(awaitingPayment).On(pay).To(paid).Do("charge card", func(ctx context.Context, _ paymentPayload) error {
+			return fsm.Enqueue(ctx, fsm.Message{Topic: "charges"})
+		})
+		b.From(paid).OnEntry("reserve stock", func(ctx context.Context, _ paymentPayload) error {
+			if err := fsm.Enqueue(ctx, fsm.Message{Topic: "reservations"}); err != nil {
+				return err
+			}
+			return hookErr
+		})
+		machine := fsm.New(b.Build(), awaitingPayment)
+		ctx, outbox := fsm.WithOutbox(t.Context())
+		require.NoError(fsm.Enqueue(ctx, fsm.Message{Topic: "earlier"}))
+
+		/* ---------------------------------- When ---------------------------------- */
+		err := machine.Fire(ctx, pay, paymentPayload{})
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.ErrorIs(err, hookErr)
+		require.Equal([]fsm.Message{{Topic: "earlier"}}, outbox.Messages())
+	})
+
+	t.Run("discards the messages of a Fire canceled after the action", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		ctx, cancel := context.WithCancel(t.Context())
+		ctx, outbox := fsm.WithOutbox(ctx)
+		b := fsm.NewBuilder[paymentState, paymentTrigger, paymentPayload]()
+		b.From(awaitingPayment).On(pay).To(paid).Do("charge card", func(ctx context.Context, _ paymentPayload) error {
+			defer cancel()
+			return fsm.Enqueue(ctx, fsm.Message{Topic: "charges"})
+		})
+		machine := fsm.New(b.Build(), awaitingPayment)
+
+		/* ---------------------------------- When ---------------------------------- */
+		err := machine.Fire(ctx, pay, paymentPayload{})
+
+		/* ---------------------------------- Then ---------------------------------- */
+		var canceled *fsm.CanceledError
+		require.ErrorAs(err, &canceled)
+		require.Empty(outbox.Messages())
+		require.Equal(awaitingPayment, machine.State())
+	})
+
+	t.Run("discards the messages of a Fire that panics", func(t *testing.T) {
+		require := require.New(t)
+
+		b := fsm.NewBuilder[paymentState, paymentTrigger, paymentPayload]()
+		b.From(awaitingPayment).On(pay).To(paid).Do("charge card", func(ctx context.Context, _ paymentPayload) error {
+			_ = fsm.Enqueue(ctx, fsm.Message{Topic: "charges"})
+			panic("gateway bug")
+		})
+		machine := fsm.New(b.Build(), awaitingPayment)
+		ctx, outbox := fsm.WithOutbox(t.Context())
+
+		require.Panics(func() { _ = machine.Fire(ctx, pay, paymentPayload{}) })
+
+		require.Empty(outbox.Messages())
+	})
+
+	t.Run("keeps the messages of a concurrent Fire when another Fire fails", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		started, release := make(chan struct{}), make(chan struct{})
+		b := fsm.NewBuilder[paymentState, paymentTrigger, paymentPayload]()
+		b.From(awaitingPayment).On(pay).To(paid).Do("charge card", func(ctx context.Context, _ paymentPayload) error {
+			_ = fsm.Enqueue(ctx, fsm.Message{Topic: "charges"})
+			close(started)
+			<-release
+			return errors.New("card declined")
+		})
+		failing := fsm.New(b.Build(), awaitingPayment)
+		succeeding := fsm.New(newOutboxSpec(0), awaitingPayment)
+		ctx, outbox := fsm.WithOutbox(t.Context())
+		failed := make(chan error)
+		go func() { failed <- failing.Fire(ctx, pay, paymentPayload{}) }()
+		<-started
+
+		/* ---------------------------------- When ---------------------------------- */
+		require.NoError(succeeding.Fire(ctx, pay, paymentPayload{}))
+		close(release)
+		err := <-failed
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.Error(err)
+		require.Equal([]string{"charges", "payments"}, topics(outbox.Messages()))
+	})
+
+	t.Run("enqueueing without an outbox fails the transition", func(t *testing.T) {
+		require := require.New(t)
+
+		machine := fsm.New(newOutboxSpec(0), awaitingPayment)
+
+		err := machine.Fire(t.Context(), pay, paymentPayload{})
+
+		require.ErrorIs(err, fsm.ErrNoOutbox)
+		require.Equal(awaitingPayment, machine.State())
+	})
+}
+
+func TestRelay(t *testing.T) {
+	t.Run("drains messages written to the store in order", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		store := fsm.NewMemoryOutboxStore()
+		publisher := &fsmtest.Publisher{}
+		for range 2 {
+			ctx, outbox := fsm.WithOutbox(t.Context())
+			require.NoError(fsm.New(newOutboxSpec(0), awaitingPayment).Fire(ctx, pay, paymentPayload{}))
+			require.NoError(outbox.WriteTo(ctx, store))
+		}
+
+		/* ---------------------------------- When ---------------------------------- */
+		published, err := fsm.NewRelay(store, publisher).WithBatchSize(3).Drain(t.Context())
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.NoError(err)
+		require.Equal(4, published)
+		require.Equal([]string{"charges", "payments", "charges", "payments"}, topics(publisher.Published()))
+		pending, err := store.Pending(t.Context(), 10)
+		require.NoError(err)
+		require.Empty(pending)
+	})
+
+	t.Run("leaves messages pending when publishing fails", func(t *testing.T) {
+		require := require.New(t)
+
+		store := fsm.NewMemoryOutboxStore()
+		require.NoError(store.Append(t.Context(), []fsm.Message{{Topic: "a"}, {Topic: "b"}}))
+		publisher := &fsmtest.Publisher{}
+		publisher.FailWith(errors.New("broker down"))
+		relay := fsm.NewRelay(store, publisher)
+
+		_, err := relay.Drain(t.Context())
+		require.Error(err)
+		publisher.FailWith(nil)
+		published, err := relay.Drain(t.Context())
+
+		require.NoError(err)
+		require.Equal(2, published)
+		require.Equal([]string{"a", "b"}, topics(publisher.Published()))
+	})
+
+	t.Run("drains one message at a time for batch sizes below 1", func(t *testing.T) {
+		require := require.New(t)
+
+		store := fsm.NewMemoryOutboxStore()
+		require.NoError(store.Append(t.Context(), []fsm.Message{{Topic: "a"}, {Topic: "b"}}))
+		publisher := &fsmtest.Publisher{}
+
+		published, err := fsm.NewRelay(store, publisher).WithBatchSize(-5).Drain(t.Context())
+
+		require.NoError(err)
+		require.Equal(2, published)
+		require.Equal([]string{"a", "b"}, topics(publisher.Published()))
+	})
+}
+
+func TestMemoryOutboxStore_Pending(t *testing.T) {
+	require := require.New(t)
+
+	store := fsm.NewMemoryOutboxStore()
+	require.NoError(store.Append(t.Context(), []fsm.Message{{Topic: "a"}, {Topic: "b"}}))
+
+	for _, limit := range []int{-1, 0} {
+		pending, err := store.Pending(t.Context(), limit)
+		require.NoError(err)
+		require.Empty(pending, "limit %d", limit)
+	}
+	pending, err := store.Pending(t.Context(), 5)
+	require.NoError(err)
+	require.Equal([]string{"a", "b"}, topics(entryMessages(pending)))
+}
+
+func entryMessages(entries []fsm.OutboxEntry) []fsm.Message {
+	messages := make([]fsm.Message, len(entries))
+	for i, e := range entries {
+		messages[i] = e.Message
+	}
+	return messages
+}
+
+func topics(messages []fsm.Message) []string {
+	var topics []string
+	for _, m := range messages {
+		topics = append(topics, m.Topic)
+	}
+	return topics
+}
@@ -0,0 +1,96 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// ErrPanicked matches, via errors.Is, every PanicError returned when panic recovery is enabled.
+var ErrPanicked = fmt.Errorf("panicked")
+
+// PanicError is returned by Fire, when the spec was built with RecoverPanics, in place of a panic raised by a guard,
+// transition action or state hook.
+type PanicError[S ~uint] struct {
+	Phase Phase  // where the panic occurred
+	State S      // the state owning the guard or hook; for actions, the state the branch is defined on
+	Value any    // the value passed to panic
+	Stack []byte // the stack of the panicking goroutine, captured at recovery
+}
+
+// Error describes the panic.
+func (e *PanicError[S]) Error() string {
+	return fmt.Sprintf("recovered panic in %v of state (%v): %v", e.Phase, e.State, e.Value)
+}
+
+// Is reports whether target is ErrPanicked.
+func (e *PanicError[S]) Is(target error) bool {
+	return target == ErrPanicked
+}
+
+// Unwrap returns the panic value if it is an error, else nil.
+func (e *PanicError[S]) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// RecoverPanics enables panic recovery. Panics in guards, transition actions and state hooks — including any
+// middleware wrapping them — are converted into a *PanicError[S] that Fire returns like any other error, so the
+// machine stays in the state it was in before Fire. Hooks that completed before the panic are not undone, exactly
+// as when a hook returns an error.
+//
+// CanFire treats a panicking guard as not matching, and Explain reports it with the Panicked outcome.
+func (b *Builder[S, T, Payload]) RecoverPanics() *Builder[S, T, Payload] {
+	b.recoverPanics = true
+	return b
+}
+
+// recoverAction wraps action so that a panic is returned as a *PanicError.
+func recoverAction[S, T ~uint, Payload any](step Step[S, T], action Action[Payload]) Action[Payload] {
+	return func(ctx context.Context, payload Payload) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = &PanicError[S]{Phase: step.Phase, State: step.State, Value: r, Stack: debug.Stack()}
+			}
+		}()
+		return action(ctx, payload)
+	}
+}
+
+// recoverCondition wraps cond so that a panic is re-raised as a *PanicError, which Fire, CanFire and Explain recover.
+// A guard cannot return an error, so the panic is the only way to unwind out of slot.match without slowing it down.
+func recoverCondition[S ~uint, Payload any](state S, cond Condition[Payload]) Condition[Payload] {
+	return func(payload Payload) bool {
+		defer func() {
+			if r := recover(); r != nil {
+				panic(&PanicError[S]{Phase: PhaseGuard, State: state, Value: r, Stack: debug.Stack()})
+			}
+		}()
+		return cond(payload)
+	}
+}
+
+// recoverGuardPanic converts a recovered *PanicError raised by a wrapped guard into an error. Any other panic value
+// is re-raised.
+func recoverGuardPanic[S ~uint](r any) error {
+	if pe, ok := r.(*PanicError[S]); ok {
+		return pe
+	}
+	panic(r)
+}
+
+// evalCondition evaluates cond for Explain, reporting a guard panic raised under RecoverPanics instead of unwinding.
+func evalCondition[S ~uint, Payload any](cond Condition[Payload], payload Payload) (matched, panicked bool) {
+	if cond == nil {
+		return true, false
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			_ = recoverGuardPanic[S](r)
+			matched, panicked = false, true
+		}
+	}()
+	return cond(payload), false
+}
@@ -0,0 +1,110 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder_RecoverPanics(t *testing.T) {
+	errCause := errors.New("cause")
+
+	tests := []struct {
+		name      string
+		configure func(*Builder[state, trigger, payload])
+		wantPhase Phase
+		wantState state
+	}{
+		{
+			name: "guard",
+			configure: func(b *Builder[state, trigger, payload]) {
+				b.From(locked).On(unlock).To(unlocked).When("explodes", func(payload) bool { panic(errCause) })
+			},
+			wantPhase: PhaseGuard,
+			wantState: locked,
+		},
+		{
+			name: "action",
+			configure: func(b *Builder[state, trigger, payload]) {
+				b.From(locked).On(unlock).To(unlocked).Do("explodes", func(context.Context, payload) error { panic(errCause) })
+			},
+			wantPhase: PhaseAction,
+			wantState: locked,
+		},
+		{
+			name: "exit hook",
+			configure: func(b *Builder[state, trigger, payload]) {
+				b.From(locked).On(unlock).To(unlocked)
+				b.From(locked).OnExit("explodes", func(context.Context, payload) error { panic(errCause) })
+			},
+			wantPhase: PhaseExit,
+			wantState: locked,
+		},
+		{
+			name: "entry hook",
+			configure: func(b *Builder[state, trigger, payload]) {
+				b.From(locked).On(unlock).To(unlocked)
+				b.From(unlocked).OnEntry("explodes", func(context.Context, payload) error { panic(errCause) })
+			},
+			wantPhase: PhaseEntry,
+			wantState: unlocked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			/* ---------------------------------- Given --------------------------------- */
+			builder := NewBuilder[state, trigger, payload]().RecoverPanics()
+			tt.configure(builder)
+			machine := New(builder.Build(), locked)
+
+			/* ---------------------------------- When ---------------------------------- */
+			err := machine.Fire(t.Context(), unlock, payload{})
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.ErrorIs(err, ErrPanicked)
+			require.ErrorIs(err, errCause, "an error panic value must be unwrappable")
+			var pe *PanicError[state]
+			require.ErrorAs(err, &pe)
+			require.Equal(tt.wantPhase, pe.Phase)
+			require.Equal(tt.wantState, pe.State)
+			require.NotEmpty(pe.Stack)
+			require.Equal(locked, machine.State(), "state must not change when a panic is recovered")
+		})
+	}
+}
+
+func TestBuilder_RecoverPanics_CanFireAndExplain(t *testing.T) {
+	require := require.New(t)
+
+	/* ---------------------------------- Given --------------------------------- */
+	builder := NewBuilder[state, trigger, payload]().RecoverPanics()
+	builder.From(locked).On(unlock).
+		To(unlocked).When("explodes", func(payload) bool { panic("boom") }).
+		Otherwise(root)
+	machine := New(builder.Build(), locked)
+
+	/* ---------------------------------- When ---------------------------------- */
+	can := machine.CanFire(unlock, payload{})
+	d := machine.Explain(unlock, payload{})
+
+	/* ---------------------------------- Then ---------------------------------- */
+	require.False(can)
+	require.True(d.Found)
+	require.False(d.Matched)
+	require.Len(d.Levels, 1)
+	require.Equal(Panicked, d.Levels[0].Branches[0].Outcome)
+	require.Equal(Skipped, d.Levels[0].Branches[1].Outcome)
+}
+
+func TestBuilder_RecoverPanics_Disabled(t *testing.T) {
+	builder := NewBuilder[state, trigger, payload]()
+	builder.From(locked).On(unlock).To(unlocked).Do("explodes", func(context.Context, payload) error { panic("boom") })
+	machine := New(builder.Build(), locked)
+
+	require.Panics(t, func() { _ = machine.Fire(t.Context(), unlock, payload{}) })
+}
@@ -0,0 +1,78 @@
+package fsm
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PlantUML returns a PlantUML state diagram of the Spec. Composite states are rendered as nested `state X { }` blocks
+// with `[*] -->` marking the initial substate, described hooks as `X : entry / desc` lines, and edges as
+// `trigger [guard] / action`, as in MermaidJSDiagram. PlantUML only lays out top to bottom or left to right, so
+// BottomToTop and RightToLeft keep the default. At most one DiagramOptions may be passed.
+func (spec *Spec[S, T, Payload]) PlantUML(opts ...DiagramOptions) string {
+	o := diagramOptions(opts)
+	d := spec.diagram(o)
+	var sb strings.Builder
+	sb.WriteString("@startuml\n")
+	switch o.Direction {
+	case TopToBottom:
+		sb.WriteString("top to bottom direction\n")
+	case LeftToRight:
+		sb.WriteString("left to right direction\n")
+	}
+	for _, st := range d.roots {
+		writePlantUMLState(&sb, d, st, 0)
+	}
+	for _, e := range d.edges {
+		sb.WriteString(plantUMLID(e.from) + " --> " + plantUMLID(e.to) + " : " + plantUMLEscape(e.label) + "\n")
+	}
+	sb.WriteString("@enduml\n")
+	return sb.String()
+}
+
+// writePlantUMLState declares state, its hook descriptions and, if it is composite, its initial substate and
+// substates in a nested block.
+func writePlantUMLState[S ~uint](sb *strings.Builder, d *diagram[S], state S, depth int) {
+	decl := indent(depth) + "state " + plantUMLDecl(state)
+	if !d.isComposite(state) {
+		sb.WriteString(decl + "\n")
+	} else {
+		sb.WriteString(decl + " {\n")
+		if initial, ok := d.initial[state]; ok {
+			sb.WriteString(indent(depth+1) + "[*] --> " + plantUMLID(initial) + "\n")
+		}
+		for _, child := range d.children[state] {
+			writePlantUMLState(sb, d, child, depth+1)
+		}
+		sb.WriteString(indent(depth) + "}\n")
+	}
+	for _, line := range d.hookLines(state) {
+		sb.WriteString(indent(depth) + plantUMLID(state) + " : " + plantUMLEscape(line) + "\n")
+	}
+}
+
+var plantUMLIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// plantUMLID returns the identifier state is referred to by: its name if that is a valid identifier, otherwise an
+// alias derived from its value.
+func plantUMLID[S ~uint](state S) string {
+	if name := fmtName(state); plantUMLIdentifier.MatchString(name) {
+		return name
+	}
+	return "state" + strconv.FormatUint(uint64(state), 10)
+}
+
+// plantUMLDecl returns the declaration of state, aliasing names that are not valid identifiers.
+func plantUMLDecl[S ~uint](state S) string {
+	name, id := fmtName(state), plantUMLID(state)
+	if name == id {
+		return name
+	}
+	return strconv.Quote(name) + " as " + id
+}
+
+// plantUMLEscape keeps a description on one line.
+func plantUMLEscape(s string) string {
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
@@ -0,0 +1,212 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DurableTimers records the state timeouts, declared with After, of machines created from a spec in a TimerStore, so
+// that deadlines spanning days survive restarts. Machines are tracked by a Repository configured WithTimers, or with
+// Track, and due timers are fired by a Poller.
+type DurableTimers[ID comparable, S, T ~uint, Payload any] struct {
+	spec  *Spec[S, T, Payload]
+	store TimerStore[ID, S, T]
+}
+
+// NewDurableTimers creates DurableTimers for machines created from spec, persisting to store. Deadlines are computed
+// with the spec's Clock.
+func NewDurableTimers[ID comparable, S, T ~uint, Payload any](
+	spec *Spec[S, T, Payload], store TimerStore[ID, S, T],
+) *DurableTimers[ID, S, T, Payload] {
+	return &DurableTimers[ID, S, T, Payload]{spec: spec, store: store}
+}
+
+// TrackedMachine is a machine whose state timeouts are recorded in a TimerStore. Fire it through TrackedMachine.Fire
+// so that timers are scheduled and cancelled as states are entered and exited. The TimerStore is updated as soon as a
+// transition succeeds, before the machine is saved; a Repository configured WithTimers updates it after the save.
+type TrackedMachine[ID comparable, S, T ~uint, Payload any] struct {
+	*Machine[S, T, Payload]
+	id      ID
+	timers  *DurableTimers[ID, S, T, Payload]
+	pending []timerOp[ID, S, T] // ops recorded by the observer during the current Fire
+}
+
+// timerOp is a Schedule (cancel false) or Cancel (cancel true) to apply to the TimerStore.
+type timerOp[ID comparable, S, T ~uint] struct {
+	timer  ScheduledTimer[ID, S, T]
+	cancel bool
+}
+
+// Track starts recording the state timeouts of machine, identified by id. The machine must have been created from the
+// DurableTimers' spec.
+func (d *DurableTimers[ID, S, T, Payload]) Track(id ID, machine *Machine[S, T, Payload]) *TrackedMachine[ID, S, T, Payload] {
+	tm := &TrackedMachine[ID, S, T, Payload]{Machine: machine, id: id, timers: d}
+	machine.Observe(Observer[S, T, Payload]{OnTransition: tm.onTransition})
+	return tm
+}
+
+// ID returns the machine's ID.
+func (tm *TrackedMachine[ID, S, T, Payload]) ID() ID {
+	return tm.id
+}
+
+// Start schedules the timers of every state in the active hierarchy, measured from now, as if the states had just
+// been entered. Call it once for a newly created machine; restored machines already have their timers stored.
+func (tm *TrackedMachine[ID, S, T, Payload]) Start(ctx context.Context) error {
+	tm.scheduleActive()
+	return tm.flush(ctx)
+}
+
+// Fire fires the trigger on the machine and, if it transitions, cancels the timers of the exited states and schedules
+// those of the entered states. A TimerStore error is returned after the transition has taken effect.
+func (tm *TrackedMachine[ID, S, T, Payload]) Fire(ctx context.Context, trigger T, payload Payload) error {
+	tm.pending = tm.pending[:0]
+	if err := tm.Machine.Fire(ctx, trigger, payload); err != nil {
+		return err
+	}
+	return tm.flush(ctx)
+}
+
+func (tm *TrackedMachine[ID, S, T, Payload]) onTransition(_ context.Context, event TransitionEvent[S, T, Payload]) {
+	for _, st := range event.Exited {
+		for _, timeout := range tm.timers.spec.stateTimeouts[st] {
+			tm.pending = append(tm.pending, timerOp[ID, S, T]{
+				timer:  ScheduledTimer[ID, S, T]{MachineID: tm.id, State: st, Trigger: timeout.trigger},
+				cancel: true,
+			})
+		}
+	}
+	for _, st := range event.Entered {
+		tm.schedule(st)
+	}
+}
+
+// scheduleActive records the scheduling of the timers of every state in the active hierarchy, outermost first.
+func (tm *TrackedMachine[ID, S, T, Payload]) scheduleActive() {
+	hierarchy := tm.ActiveHierarchy()
+	for i := len(hierarchy) - 1; i >= 0; i-- {
+		tm.schedule(hierarchy[i])
+	}
+}
+
+func (tm *TrackedMachine[ID, S, T, Payload]) schedule(state S) {
+	if uint(state) >= uint(len(tm.timers.spec.stateTimeouts)) {
+		return
+	}
+	now := tm.timers.spec.clock.Now()
+	for _, timeout := range tm.timers.spec.stateTimeouts[state] {
+		tm.pending = append(tm.pending, timerOp[ID, S, T]{timer: ScheduledTimer[ID, S, T]{
+			MachineID: tm.id,
+			State:     state,
+			Trigger:   timeout.trigger,
+			At:        now.Add(timeout.after),
+		}})
+	}
+}
+
+// flush applies the pending ops in order, so that a self-transition's cancel is followed by its re-schedule.
+func (tm *TrackedMachine[ID, S, T, Payload]) flush(ctx context.Context) error {
+	defer func() { tm.pending = tm.pending[:0] }()
+	for _, op := range tm.pending {
+		var err error
+		if op.cancel {
+			err = tm.timers.store.Cancel(ctx, op.timer)
+		} else {
+			err = tm.timers.store.Schedule(ctx, op.timer)
+		}
+		if err != nil {
+			return fmt.Errorf("updating timer for trigger (%v) of state (%v): %w", op.timer.Trigger, op.timer.State, err)
+		}
+	}
+	return nil
+}
+
+// Poller fires due durable timers. For each due timer it loads the machine from a Store, fires the timer's trigger,
+// saves the machine with a version check and only then updates the TimerStore.
+type Poller[ID comparable, S, T ~uint, Payload any] struct {
+	timers  *DurableTimers[ID, S, T, Payload]
+	store   Store[ID, S, T]
+	payload func(ScheduledTimer[ID, S, T]) Payload
+}
+
+// NewPoller creates a Poller firing the timers recorded by timers on machines persisted in store, usually the Store of
+// a Repository configured WithTimers.
+func NewPoller[ID comparable, S, T ~uint, Payload any](
+	timers *DurableTimers[ID, S, T, Payload], store Store[ID, S, T],
+) *Poller[ID, S, T, Payload] {
+	return &Poller[ID, S, T, Payload]{timers: timers, store: store}
+}
+
+// WithPayload sets the function providing the payload a due timer's trigger is fired with. Without it, the zero
+// Payload is used.
+func (p *Poller[ID, S, T, Payload]) WithPayload(payload func(ScheduledTimer[ID, S, T]) Payload) *Poller[ID, S, T, Payload] {
+	p.payload = payload
+	return p
+}
+
+// Poll fires every timer due now and returns how many it fired. A timer is removed once its transition is saved, or
+// once firing it fails, so a failing timer is not retried forever; declare a Retry policy on the actions involved to
+// retry transient failures. A timer whose transition cannot be saved, because the machine was saved concurrently
+// (wrapping ErrVersionConflict) or the Store failed, stays due and is retried by the next Poll, running the
+// transition's actions again. Timers whose state is no longer active are removed without firing. Errors are joined.
+func (p *Poller[ID, S, T, Payload]) Poll(ctx context.Context) (int, error) {
+	due, err := p.timers.store.Due(ctx, p.timers.spec.clock.Now())
+	if err != nil {
+		return 0, fmt.Errorf("loading due timers: %w", err)
+	}
+	fired := 0
+	var errs []error
+	for _, timer := range due {
+		ok, err := p.fire(ctx, timer)
+		if ok {
+			fired++
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("firing timer for trigger (%v) of state (%v) on machine %v: %w",
+				timer.Trigger, timer.State, timer.MachineID, err))
+		}
+	}
+	return fired, errors.Join(errs...)
+}
+
+// Run polls every interval, on the spec's Clock, until ctx is done, then returns ctx's error. Poll errors are passed to
+// onError, which may be nil.
+func (p *Poller[ID, S, T, Payload]) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	for {
+		if _, err := p.Poll(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+		if err := p.timers.spec.clock.Sleep(ctx, interval); err != nil {
+			return err
+		}
+	}
+}
+
+// fire loads the timer's machine, fires its trigger and saves the machine, reporting whether the transition was saved.
+// The TimerStore is updated only after the save, so that a transition that is not saved leaves no timers behind.
+func (p *Poller[ID, S, T, Payload]) fire(ctx context.Context, timer ScheduledTimer[ID, S, T]) (bool, error) {
+	record, err := p.store.Load(ctx, timer.MachineID)
+	if err != nil {
+		return false, fmt.Errorf("loading machine: %w", err)
+	}
+	tm := p.timers.Track(timer.MachineID, Restore(p.timers.spec, record.Snapshot))
+	// Remove the due timer first, so that a transition re-scheduling the same timer is not undone.
+	tm.pending = append(tm.pending, timerOp[ID, S, T]{timer: timer, cancel: true})
+	if !tm.IsIn(timer.State) {
+		return false, tm.flush(ctx) // stale: the state was exited without the timer being cancelled
+	}
+	var payload Payload
+	if p.payload != nil {
+		payload = p.payload(timer)
+	}
+	if err := tm.Machine.Fire(ctx, timer.Trigger, payload); err != nil {
+		tm.pending = tm.pending[:1]
+		return false, errors.Join(err, tm.flush(ctx))
+	}
+	if err := p.store.Save(ctx, timer.MachineID, tm.Snapshot(), record.Version); err != nil {
+		return false, fmt.Errorf("saving machine: %w", err)
+	}
+	return true, tm.flush(ctx)
+}
@@ -0,0 +1,166 @@
+package fsm_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tobbstr/fsm"
+	"github.com/tobbstr/fsm/fsmtest"
+)
+
+// racingStore is a fsm.Store that, on the next Save, first runs race, to simulate a concurrent save.
+type racingStore struct {
+	*fsm.MemoryStore[string, orderState, orderTrigger]
+	race func()
+}
+
+func (s *racingStore) Save(ctx context.Context, id string, snapshot fsm.Snapshot[orderState, orderTrigger], expected uint64) error {
+	if race := s.race; race != nil {
+		s.race = nil
+		race()
+	}
+	return s.MemoryStore.Save(ctx, id, snapshot, expected)
+}
+
+func TestDurableTimers(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	type timer = fsm.ScheduledTimer[string, orderState, orderTrigger]
+
+	t.Run("schedules timers on entry and cancels them on exit", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		clock := fsmtest.NewClock(start)
+		store := fsm.NewMemoryTimerStore[string, orderState, orderTrigger]()
+		timers := fsm.NewDurableTimers(newOrderSpec(clock), store)
+		machine := timers.Track("order-1", fsm.New(newOrderSpec(clock), awaitingOrderPayment))
+
+		/* ---------------------------------- When ---------------------------------- */
+		require.NoError(machine.Start(t.Context()))
+		clock.Advance(time.Minute)
+		require.NoError(machine.Fire(t.Context(), payOrder, orderPayload{}))
+
+		/* ---------------------------------- Then ---------------------------------- */
+		due, err := store.Due(t.Context(), start.Add(48*time.Hour))
+		require.NoError(err)
+		require.Equal([]timer{
+			{MachineID: "order-1", State: orderOpen, Trigger: closeOrder, At: start.Add(24 * time.Hour)},
+		}, due)
+	})
+
+	t.Run("poller fires due timers on machines loaded from the store", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		clock := fsmtest.NewClock(start)
+		spec := newOrderSpec(clock)
+		store := fsm.NewMemoryTimerStore[string, orderState, orderTrigger]()
+		machines := fsm.NewMemoryStore[string, orderState, orderTrigger]()
+		timers := fsm.NewDurableTimers(spec, store)
+		for _, id := range []string{"order-1", "order-2"} {
+			machine := timers.Track(id, fsm.New(spec, awaitingOrderPayment))
+			require.NoError(machine.Start(t.Context()))
+			require.NoError(machines.Save(t.Context(), id, machine.Snapshot(), 0))
+		}
+		// order-2 is paid in the meantime, by another process that restored it.
+		paid := timers.Track("order-2", fsm.New(spec, awaitingOrderPayment))
+		require.NoError(paid.Fire(t.Context(), payOrder, orderPayload{}))
+		require.NoError(machines.Save(t.Context(), "order-2", paid.Snapshot(), 1))
+
+		var payloads []orderPayload
+		poller := fsm.NewPoller(timers, machines).
+			WithPayload(func(timer fsm.ScheduledTimer[string, orderState, orderTrigger]) orderPayload {
+				payloads = append(payloads, orderPayload{reason: "expired " + timer.MachineID})
+				return payloads[len(payloads)-1]
+			})
+
+		/* ---------------------------------- When ---------------------------------- */
+		clock.Advance(29 * time.Minute)
+		firedEarly, err := poller.Poll(t.Context())
+		require.NoError(err)
+		clock.Advance(time.Minute)
+		fired, err := poller.Poll(t.Context())
+		require.NoError(err)
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.Zero(firedEarly)
+		require.Equal(1, fired)
+		require.Equal([]orderPayload{{reason: "expired order-1"}}, payloads)
+		record, err := machines.Load(t.Context(), "order-1")
+		require.NoError(err)
+		require.Equal(orderExpired, record.Snapshot.State)
+		require.Equal(uint64(2), record.Version)
+		record, err = machines.Load(t.Context(), "order-2")
+		require.NoError(err)
+		require.Equal(orderPaid, record.Snapshot.State)
+		due, err := store.Due(t.Context(), start.Add(48*time.Hour))
+		require.NoError(err)
+		require.Equal([]timer{
+			{MachineID: "order-2", State: orderOpen, Trigger: closeOrder, At: start.Add(24 * time.Hour)},
+		}, due, "order-1 left orderOpen, so only order-2's close timer remains")
+	})
+
+	t.Run("poller drops timers of states no longer active", func(t *testing.T) {
+		require := require.New(t)
+
+		clock := fsmtest.NewClock(start)
+		spec := newOrderSpec(clock)
+		store := fsm.NewMemoryTimerStore[string, orderState, orderTrigger]()
+		machines := fsm.NewMemoryStore[string, orderState, orderTrigger]()
+		require.NoError(machines.Save(t.Context(), "order-1", fsm.Snapshot[orderState, orderTrigger]{State: orderPaid}, 0))
+		require.NoError(store.Schedule(t.Context(), timer{
+			MachineID: "order-1", State: awaitingOrderPayment, Trigger: expireOrder, At: start,
+		}))
+
+		fired, err := fsm.NewPoller(fsm.NewDurableTimers(spec, store), machines).Poll(t.Context())
+
+		require.NoError(err)
+		require.Zero(fired)
+		due, err := store.Due(t.Context(), start)
+		require.NoError(err)
+		require.Empty(due)
+	})
+	t.Run("poller keeps timers whose transition loses a version conflict", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		clock := fsmtest.NewClock(start)
+		spec := newOrderSpec(clock)
+		store := fsm.NewMemoryTimerStore[string, orderState, orderTrigger]()
+		machines := &racingStore{MemoryStore: fsm.NewMemoryStore[string, orderState, orderTrigger]()}
+		timers := fsm.NewDurableTimers(spec, store)
+		machine := timers.Track("order-1", fsm.New(spec, awaitingOrderPayment))
+		require.NoError(machine.Start(t.Context()))
+		require.NoError(machines.Save(t.Context(), "order-1", machine.Snapshot(), 0))
+		machines.race = func() {
+			require.NoError(machines.MemoryStore.Save(t.Context(), "order-1", machine.Snapshot(), 1))
+		}
+		poller := fsm.NewPoller(timers, machines)
+		clock.Advance(30 * time.Minute)
+
+		/* ---------------------------------- When ---------------------------------- */
+		firedInRace, raceErr := poller.Poll(t.Context())
+		dueAfterRace, err := store.Due(t.Context(), start.Add(48*time.Hour))
+		require.NoError(err)
+		fired, err := poller.Poll(t.Context())
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.ErrorIs(raceErr, fsm.ErrVersionConflict)
+		require.Zero(firedInRace)
+		require.Equal([]timer{
+			{MachineID: "order-1", State: awaitingOrderPayment, Trigger: expireOrder, At: start.Add(30 * time.Minute)},
+			{MachineID: "order-1", State: orderOpen, Trigger: closeOrder, At: start.Add(24 * time.Hour)},
+		}, dueAfterRace, "the unsaved transition must leave the timers untouched")
+		require.NoError(err)
+		require.Equal(1, fired)
+		record, err := machines.Load(t.Context(), "order-1")
+		require.NoError(err)
+		require.Equal(orderExpired, record.Snapshot.State)
+		require.Equal(uint64(3), record.Version)
+		due, err := store.Due(t.Context(), start.Add(48*time.Hour))
+		require.NoError(err)
+		require.Empty(due)
+	})
+}
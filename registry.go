@@ -0,0 +1,107 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnresolvedName is wrapped by the errors of importers for names that are missing from the Registry.
+var ErrUnresolvedName = errors.New("unresolved name")
+
+// Registry resolves the names used by imported specs to states, triggers, conditions and actions. Conditions and
+// actions keep their names as descriptions, so exporting an imported spec reproduces the names.
+type Registry[S, T ~uint, Payload any] struct {
+	States     map[string]S
+	Triggers   map[string]T
+	Conditions map[string]Condition[Payload]
+	Actions    map[string]Action[Payload]
+}
+
+// Names maps the %v formatting of each value to the value, e.g. Names(Created, Paid, Shipped) for Registry.States.
+func Names[V ~uint](values ...V) map[string]V {
+	names := make(map[string]V, len(values))
+	for _, v := range values {
+		names[fmt.Sprint(v)] = v
+	}
+	return names
+}
+
+// State returns the state called name, or an error wrapping ErrUnresolvedName.
+func (r Registry[S, T, Payload]) State(name string) (S, error) {
+	return lookup("state", name, r.States)
+}
+
+// Trigger returns the trigger called name, or an error wrapping ErrUnresolvedName.
+func (r Registry[S, T, Payload]) Trigger(name string) (T, error) {
+	return lookup("trigger", name, r.Triggers)
+}
+
+// Condition returns the condition called name, or an error wrapping ErrUnresolvedName.
+func (r Registry[S, T, Payload]) Condition(name string) (Condition[Payload], error) {
+	return lookup("condition", name, r.Conditions)
+}
+
+// Action returns the action called name, or an error wrapping ErrUnresolvedName.
+func (r Registry[S, T, Payload]) Action(name string) (Action[Payload], error) {
+	return lookup("action", name, r.Actions)
+}
+
+func lookup[V any](kind, name string, names map[string]V) (V, error) {
+	v, ok := names[name]
+	if !ok {
+		return v, fmt.Errorf("%w: %s %q", ErrUnresolvedName, kind, name)
+	}
+	return v, nil
+}
+
+// resolver looks names up in a Registry, collecting an error for each name that is missing rather than stopping at the
+// first, so that an import reports every problem at once.
+type resolver[S, T ~uint, Payload any] struct {
+	registry *Registry[S, T, Payload]
+	errs     []error
+}
+
+// state returns the state called name. where locates the reference in the imported document.
+func (r *resolver[S, T, Payload]) state(where, name string) S {
+	v, err := r.registry.State(name)
+	r.check(where, err)
+	return v
+}
+
+// trigger returns the trigger called name.
+func (r *resolver[S, T, Payload]) trigger(where, name string) T {
+	v, err := r.registry.Trigger(name)
+	r.check(where, err)
+	return v
+}
+
+// condition returns the condition called name.
+func (r *resolver[S, T, Payload]) condition(where, name string) Condition[Payload] {
+	v, err := r.registry.Condition(name)
+	r.check(where, err)
+	return v
+}
+
+// action returns the action called name.
+func (r *resolver[S, T, Payload]) action(where, name string) Action[Payload] {
+	v, err := r.registry.Action(name)
+	r.check(where, err)
+	return v
+}
+
+// check records err, if any, at where.
+func (r *resolver[S, T, Payload]) check(where string, err error) {
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s: %w", where, err))
+	}
+}
+
+// fail records an error at where.
+func (r *resolver[S, T, Payload]) fail(where, format string, args ...any) {
+	r.errs = append(r.errs, fmt.Errorf("%s: %s", where, fmt.Sprintf(format, args...)))
+}
+
+// err returns the collected errors joined, or nil.
+func (r *resolver[S, T, Payload]) err() error {
+	return errors.Join(r.errs...)
+}
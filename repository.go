@@ -0,0 +1,130 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrMachineNotFound is returned when a Store has no machine with the requested ID.
+	ErrMachineNotFound = errors.New("machine not found")
+	// ErrVersionConflict is returned when a machine is saved based on a version that is no longer current, because it
+	// was saved concurrently.
+	ErrVersionConflict = errors.New("version conflict")
+)
+
+// Record is a stored machine: its snapshot and version. Versions start at 1 and increase by one with every save.
+type Record[S, T ~uint] struct {
+	Snapshot Snapshot[S, T] `json:"snapshot"`
+	Version  uint64         `json:"version"`
+}
+
+// Store persists machine snapshots by ID with optimistic locking. Implementations must be safe for concurrent use;
+// fsmtest.RunStoreTests checks an implementation against this contract.
+type Store[ID comparable, S, T ~uint] interface {
+	// Load returns the machine's record, or an error wrapping ErrMachineNotFound.
+	Load(ctx context.Context, id ID) (Record[S, T], error)
+	// Save stores snapshot as version expected+1, provided the stored version is expected. An expected version of 0
+	// creates the machine, provided it does not exist. Otherwise it returns an error wrapping ErrVersionConflict and
+	// leaves the store unchanged.
+	Save(ctx context.Context, id ID, snapshot Snapshot[S, T], expected uint64) error
+}
+
+// Repository loads machines created from a spec from a Store, fires triggers on them and saves the result, replacing
+// the load → New → Fire → save glue otherwise written around every machine.
+type Repository[ID comparable, S, T ~uint, Payload any] struct {
+	spec   *Spec[S, T, Payload]
+	store  Store[ID, S, T]
+	timers *DurableTimers[ID, S, T, Payload]
+}
+
+// NewRepository creates a Repository for machines created from spec, persisted in store.
+func NewRepository[ID comparable, S, T ~uint, Payload any](
+	spec *Spec[S, T, Payload], store Store[ID, S, T],
+) *Repository[ID, S, T, Payload] {
+	return &Repository[ID, S, T, Payload]{spec: spec, store: store}
+}
+
+// WithTimers records the state timeouts of the repository's machines in timers, created for the same spec, so that a
+// Poller on the repository's Store fires them. Create schedules the timers of the initial hierarchy, and Fire and
+// Update cancel those of the exited states and schedule those of the entered states. The TimerStore is updated only
+// after the machine is saved; a TimerStore error is returned after the save has taken effect.
+func (r *Repository[ID, S, T, Payload]) WithTimers(timers *DurableTimers[ID, S, T, Payload]) *Repository[ID, S, T, Payload] {
+	r.timers = timers
+	return r
+}
+
+// Create stores a new machine in the initial state and returns it. It returns an error wrapping ErrVersionConflict if
+// a machine with the ID already exists.
+func (r *Repository[ID, S, T, Payload]) Create(ctx context.Context, id ID, initialState S) (*Machine[S, T, Payload], error) {
+	m := New(r.spec, initialState)
+	tm := r.track(id, m)
+	if tm != nil {
+		tm.scheduleActive()
+	}
+	if err := r.store.Save(ctx, id, m.Snapshot(), 0); err != nil {
+		return nil, fmt.Errorf("creating machine %v: %w", id, err)
+	}
+	if err := r.flush(ctx, tm); err != nil {
+		return m, fmt.Errorf("machine %v: %w", id, err)
+	}
+	return m, nil
+}
+
+// Load restores the machine with the ID and returns it with its version.
+func (r *Repository[ID, S, T, Payload]) Load(ctx context.Context, id ID) (*Machine[S, T, Payload], uint64, error) {
+	record, err := r.store.Load(ctx, id)
+	if err != nil {
+		return nil, 0, fmt.Errorf("loading machine %v: %w", id, err)
+	}
+	return Restore(r.spec, record.Snapshot), record.Version, nil
+}
+
+// Fire loads the machine with the ID, fires the trigger and saves the machine if the transition succeeds, returning
+// the new state. Nothing is saved if Fire fails. If the machine was saved concurrently since it was loaded, the error
+// wraps ErrVersionConflict; the transition's side effects have then run, so retry only if they are idempotent.
+func (r *Repository[ID, S, T, Payload]) Fire(ctx context.Context, id ID, trigger T, payload Payload) (S, error) {
+	return r.Update(ctx, id, func(ctx context.Context, m *Machine[S, T, Payload]) error {
+		return m.Fire(ctx, trigger, payload)
+	})
+}
+
+// Update loads the machine with the ID, passes it to update and saves it if update returns nil, returning the new
+// state. Use it to fire several triggers, or to inspect the machine, under a single version check.
+func (r *Repository[ID, S, T, Payload]) Update(
+	ctx context.Context, id ID, update func(ctx context.Context, m *Machine[S, T, Payload]) error,
+) (S, error) {
+	m, version, err := r.Load(ctx, id)
+	if err != nil {
+		var zero S
+		return zero, err
+	}
+	tm := r.track(id, m)
+	if err := update(ctx, m); err != nil {
+		return m.State(), err
+	}
+	if err := r.store.Save(ctx, id, m.Snapshot(), version); err != nil {
+		return m.State(), fmt.Errorf("saving machine %v: %w", id, err)
+	}
+	if err := r.flush(ctx, tm); err != nil {
+		return m.State(), fmt.Errorf("machine %v: %w", id, err)
+	}
+	return m.State(), nil
+}
+
+// track starts recording the machine's timer ops, if the repository has timers.
+func (r *Repository[ID, S, T, Payload]) track(id ID, m *Machine[S, T, Payload]) *TrackedMachine[ID, S, T, Payload] {
+	if r.timers == nil {
+		return nil
+	}
+	return r.timers.Track(id, m)
+}
+
+// flush applies the timer ops recorded by tm, which is nil if the repository has no timers.
+func (r *Repository[ID, S, T, Payload]) flush(ctx context.Context, tm *TrackedMachine[ID, S, T, Payload]) error {
+	if tm == nil {
+		return nil
+	}
+	return tm.flush(ctx)
+}
@@ -0,0 +1,172 @@
+package fsm_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tobbstr/fsm"
+	"github.com/tobbstr/fsm/fsmtest"
+)
+
+func TestStores(t *testing.T) {
+	id := func(n int) string { return fmt.Sprint("order-", n) }
+
+	t.Run("memory", func(t *testing.T) {
+		fsmtest.RunStoreTests(t, func(t *testing.T) fsm.Store[string, orderState, orderTrigger] {
+			return fsm.NewMemoryStore[string, orderState, orderTrigger]()
+		}, id)
+	})
+	t.Run("file", func(t *testing.T) {
+		fsmtest.RunStoreTests(t, func(t *testing.T) fsm.Store[string, orderState, orderTrigger] {
+			return fsm.NewFileStore[string, orderState, orderTrigger](filepath.Join(t.TempDir(), "machines.json"))
+		}, id)
+	})
+}
+
+func TestRepository(t *testing.T) {
+	newRepository := func() (*fsm.Repository[string, orderState, orderTrigger, orderPayload], *fsm.MemoryStore[string, orderState, orderTrigger]) {
+		store := fsm.NewMemoryStore[string, orderState, orderTrigger]()
+		return fsm.NewRepository(newOrderSpec(fsm.SystemClock{}), store), store
+	}
+
+	t.Run("fires on the loaded machine and saves the new state", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		repo, store := newRepository()
+		_, err := repo.Create(t.Context(), "order-1", awaitingOrderPayment)
+		require.NoError(err)
+
+		/* ---------------------------------- When ---------------------------------- */
+		state, err := repo.Fire(t.Context(), "order-1", payOrder, orderPayload{})
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.NoError(err)
+		require.Equal(orderPaid, state)
+		record, err := store.Load(t.Context(), "order-1")
+		require.NoError(err)
+		require.Equal(fsm.Record[orderState, orderTrigger]{Snapshot: fsm.Snapshot[orderState, orderTrigger]{State: orderPaid}, Version: 2}, record)
+	})
+
+	t.Run("saves nothing when firing fails", func(t *testing.T) {
+		require := require.New(t)
+
+		repo, store := newRepository()
+		_, err := repo.Create(t.Context(), "order-1", orderExpired)
+		require.NoError(err)
+
+		state, err := repo.Fire(t.Context(), "order-1", payOrder, orderPayload{})
+
+		require.ErrorIs(err, fsm.ErrNotFound)
+		require.Equal(orderExpired, state)
+		record, err := store.Load(t.Context(), "order-1")
+		require.NoError(err)
+		require.Equal(uint64(1), record.Version)
+	})
+
+	t.Run("rejects a save based on a stale version", func(t *testing.T) {
+		require := require.New(t)
+
+		repo, _ := newRepository()
+		_, err := repo.Create(t.Context(), "order-1", awaitingOrderPayment)
+		require.NoError(err)
+
+		_, err = repo.Update(t.Context(), "order-1", func(ctx context.Context, m *fsm.Machine[orderState, orderTrigger, orderPayload]) error {
+			// Another process expires the order while this one is paying it.
+			_, err := repo.Fire(ctx, "order-1", expireOrder, orderPayload{})
+			require.NoError(err)
+			return m.Fire(ctx, payOrder, orderPayload{})
+		})
+
+		require.ErrorIs(err, fsm.ErrVersionConflict)
+		m, version, err := repo.Load(t.Context(), "order-1")
+		require.NoError(err)
+		require.Equal(orderExpired, m.State())
+		require.Equal(uint64(2), version)
+	})
+
+	t.Run("reports missing and duplicate machines", func(t *testing.T) {
+		require := require.New(t)
+
+		repo, _ := newRepository()
+		_, err := repo.Fire(t.Context(), "order-1", payOrder, orderPayload{})
+		require.ErrorIs(err, fsm.ErrMachineNotFound)
+
+		_, err = repo.Create(t.Context(), "order-1", awaitingOrderPayment)
+		require.NoError(err)
+		_, err = repo.Create(t.Context(), "order-1", awaitingOrderPayment)
+		require.ErrorIs(err, fsm.ErrVersionConflict)
+	})
+	t.Run("updates durable timers after saving", func(t *testing.T) {
+		require := require.New(t)
+		type timer = fsm.ScheduledTimer[string, orderState, orderTrigger]
+
+		/* ---------------------------------- Given --------------------------------- */
+		start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+		clock := fsmtest.NewClock(start)
+		spec := newOrderSpec(clock)
+		timerStore := fsm.NewMemoryTimerStore[string, orderState, orderTrigger]()
+		timers := fsm.NewDurableTimers(spec, timerStore)
+		store := fsm.NewMemoryStore[string, orderState, orderTrigger]()
+		repo := fsm.NewRepository(spec, store).WithTimers(timers)
+
+		/* ---------------------------------- When ---------------------------------- */
+		for _, id := range []string{"order-1", "order-2"} {
+			_, err := repo.Create(t.Context(), id, awaitingOrderPayment)
+			require.NoError(err)
+		}
+		clock.Advance(time.Minute)
+		_, err := repo.Create(t.Context(), "order-1", awaitingOrderPayment)
+		require.ErrorIs(err, fsm.ErrVersionConflict)
+		_, err = repo.Fire(t.Context(), "order-2", payOrder, orderPayload{})
+		require.NoError(err)
+		clock.Advance(29 * time.Minute)
+		fired, err := fsm.NewPoller(timers, store).Poll(t.Context())
+		require.NoError(err)
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.Equal(1, fired)
+		m, _, err := repo.Load(t.Context(), "order-1")
+		require.NoError(err)
+		require.Equal(orderExpired, m.State())
+		due, err := timerStore.Due(t.Context(), start.Add(48*time.Hour))
+		require.NoError(err)
+		require.Equal([]timer{
+			{MachineID: "order-2", State: orderOpen, Trigger: closeOrder, At: start.Add(24 * time.Hour)},
+		}, due, "the duplicate create must not reschedule order-1's timers")
+	})
+
+	t.Run("leaves durable timers untouched when the save fails", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		timerStore := fsm.NewMemoryTimerStore[string, orderState, orderTrigger]()
+		spec := newOrderSpec(fsm.SystemClock{})
+		repo := fsm.NewRepository(spec, fsm.NewMemoryStore[string, orderState, orderTrigger]()).
+			WithTimers(fsm.NewDurableTimers(spec, timerStore))
+		_, err := repo.Create(t.Context(), "order-1", awaitingOrderPayment)
+		require.NoError(err)
+		before, err := timerStore.Due(t.Context(), time.Now().Add(48*time.Hour))
+		require.NoError(err)
+
+		/* ---------------------------------- When ---------------------------------- */
+		_, err = repo.Update(t.Context(), "order-1", func(ctx context.Context, m *fsm.Machine[orderState, orderTrigger, orderPayload]) error {
+			_, err := repo.Update(ctx, "order-1", func(context.Context, *fsm.Machine[orderState, orderTrigger, orderPayload]) error {
+				return nil // saved concurrently without transitioning
+			})
+			require.NoError(err)
+			return m.Fire(ctx, payOrder, orderPayload{})
+		})
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.ErrorIs(err, fsm.ErrVersionConflict)
+		after, err := timerStore.Due(t.Context(), time.Now().Add(48*time.Hour))
+		require.NoError(err)
+		require.Len(before, 2)
+		require.Equal(before, after)
+	})
+}
@@ -0,0 +1,170 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ErrTimeout is wrapped by the error of an action or hook attempt that failed after exceeding its Timeout.
+var ErrTimeout = fmt.Errorf("timed out")
+
+// RetryPolicy declares how a failing action or hook is retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first. Values below 2 disable retrying.
+	MaxAttempts int
+	// Backoff returns the delay before the attempt following the given 1-based attempt. Nil means no delay.
+	Backoff func(attempt int) time.Duration
+	// RetryIf reports whether an error is worth retrying. Nil retries every error.
+	RetryIf func(err error) bool
+}
+
+// ExponentialBackoff returns a RetryPolicy.Backoff that starts at initial and doubles per attempt, capped at max.
+func ExponentialBackoff(initial, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := initial
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		return min(d, max)
+	}
+}
+
+// AttemptsError is returned by an action or hook with a Retry policy that failed. It reports how many attempts were
+// made and wraps the last attempt's error.
+type AttemptsError struct {
+	Attempts int
+	Err      error
+}
+
+// Error describes the failure.
+func (e *AttemptsError) Error() string {
+	return fmt.Sprintf("failed after %d attempt(s): %v", e.Attempts, e.Err)
+}
+
+// Unwrap returns the last attempt's error.
+func (e *AttemptsError) Unwrap() error {
+	return e.Err
+}
+
+// resilience holds the Timeout and Retry modifiers of an action or hook.
+type resilience struct {
+	timeout time.Duration // zero means no timeout
+	retry   *RetryPolicy  // nil means a single attempt
+}
+
+// hookStep is returned by fromStep.OnEntry and fromStep.OnExit. It allows the hook just added to be modified with
+// Timeout and Retry, and otherwise continues the state definition like fromStep.
+type hookStep[S, T ~uint, Payload any] struct {
+	*fromStep[S, T, Payload]
+	sb *stateBuilder[S, T, Payload]
+}
+
+// Timeout bounds each attempt of the current branch's action: its context is cancelled once d has elapsed on the
+// spec's Clock, and a failed attempt that exceeded d wraps ErrTimeout.
+func (bs *branchStep[S, T, Payload]) Timeout(d time.Duration) *branchStep[S, T, Payload] {
+	bs.cur.resilience.timeout = d
+	return bs
+}
+
+// Retry re-runs the current branch's action according to policy when it fails. Only the action is re-run; exit hooks
+// that already completed are not.
+func (bs *branchStep[S, T, Payload]) Retry(policy RetryPolicy) *branchStep[S, T, Payload] {
+	bs.cur.resilience.retry = &policy
+	return bs
+}
+
+// Timeout bounds each attempt of the hook just added; see branchStep.Timeout.
+func (hs *hookStep[S, T, Payload]) Timeout(d time.Duration) *hookStep[S, T, Payload] {
+	hs.sb.resilience.timeout = d
+	return hs
+}
+
+// Retry re-runs the hook just added according to policy when it fails; see branchStep.Retry.
+func (hs *hookStep[S, T, Payload]) Retry(policy RetryPolicy) *hookStep[S, T, Payload] {
+	hs.sb.resilience.retry = &policy
+	return hs
+}
+
+// withResilience applies the timeout and retry policy to action. A nil action, or one without modifiers, is returned
+// unchanged.
+func (b *Builder[S, T, Payload]) withResilience(step Step[S, T], r resilience, action Action[Payload]) Action[Payload] {
+	if action == nil {
+		return nil
+	}
+	clock := b.clockOrDefault()
+	if r.timeout > 0 {
+		action = withTimeout(clock, r.timeout, action)
+	}
+	if r.retry != nil {
+		action = withRetry(clock, step, *r.retry, b.observers, action)
+	}
+	return action
+}
+
+// clockOrDefault returns the configured Clock, or SystemClock if none was set.
+func (b *Builder[S, T, Payload]) clockOrDefault() Clock {
+	if b.clock == nil {
+		return SystemClock{}
+	}
+	return b.clock
+}
+
+func withTimeout[Payload any](clock Clock, d time.Duration, action Action[Payload]) Action[Payload] {
+	timeoutErr := fmt.Errorf("%w after %v", ErrTimeout, d)
+	return func(ctx context.Context, payload Payload) error {
+		ctx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+		timer := clock.AfterFunc(d, func() { cancel(timeoutErr) })
+		defer timer.Stop()
+		err := action(ctx, payload)
+		if err != nil && context.Cause(ctx) == timeoutErr {
+			return fmt.Errorf("%w: %w", timeoutErr, err)
+		}
+		return err
+	}
+}
+
+func withRetry[S, T ~uint, Payload any](
+	clock Clock, step Step[S, T], policy RetryPolicy, observers []Observer[S, T, Payload], action Action[Payload],
+) Action[Payload] {
+	return func(ctx context.Context, payload Payload) error {
+		outbox := outboxFrom(ctx)
+		for attempt := 1; ; attempt++ {
+			err := attemptBuffered(ctx, outbox, action, payload)
+			last := err == nil || attempt >= policy.MaxAttempts || (policy.RetryIf != nil && !policy.RetryIf(err))
+			var backoff time.Duration
+			if !last && policy.Backoff != nil {
+				backoff = policy.Backoff(attempt)
+			}
+			for _, o := range observers {
+				if o.OnAttempt != nil {
+					o.OnAttempt(ctx, AttemptEvent[S, T]{Step: step, Attempt: attempt, Err: err, Backoff: backoff})
+				}
+			}
+			if err == nil {
+				return nil
+			}
+			if last {
+				return &AttemptsError{Attempts: attempt, Err: err}
+			}
+			if sleepErr := clock.Sleep(ctx, backoff); sleepErr != nil {
+				return &AttemptsError{Attempts: attempt, Err: err}
+			}
+		}
+	}
+}
+
+// attemptBuffered runs one attempt of action. If ctx carries outbox, the attempt's messages are handed to it only if the
+// attempt succeeds, so that a failed attempt's messages are never published.
+func attemptBuffered[Payload any](ctx context.Context, outbox *Outbox, action Action[Payload], payload Payload) error {
+	if outbox == nil {
+		return action(ctx, payload)
+	}
+	ctx, scoped := outbox.scope(ctx)
+	if err := action(ctx, payload); err != nil {
+		return err
+	}
+	outbox.commit(scoped)
+	return nil
+}
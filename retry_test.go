@@ -0,0 +1,203 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tobbstr/fsm"
+	"github.com/tobbstr/fsm/fsmtest"
+)
+
+type paymentState uint
+
+const (
+	awaitingPayment paymentState = iota
+	paid
+)
+
+type paymentTrigger uint
+
+const pay paymentTrigger = 0
+
+type paymentPayload struct{}
+
+func TestBranchStep_Retry(t *testing.T) {
+	errDeclined := errors.New("declined")
+
+	t.Run("retries with backoff until the action succeeds, without re-running exit hooks", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		clock := fsmtest.NewClock(time.Unix(0, 0))
+		var events []fsm.AttemptEvent[paymentState, paymentTrigger]
+		attempts, exits := 0, 0
+		builder := fsm.NewBuilder[paymentState, paymentTrigger, paymentPayload]().
+			WithClock(clock).
+			Observe(fsm.Observer[paymentState, paymentTrigger, paymentPayload]{
+				OnAttempt: func(ctx context.Context, e fsm.AttemptEvent[paymentState, paymentTrigger]) {
+					events = append(events, e)
+				},
+			})
+		builder.From(awaitingPayment).On(pay).To(paid).
+			Do("charge card", func(context.Context, paymentPayload) error {
+				attempts++
+				if attempts < 3 {
+					return errDeclined
+				}
+				return nil
+			}).
+			Retry(fsm.RetryPolicy{MaxAttempts: 5, Backoff: fsm.ExponentialBackoff(time.Second, time.Minute)})
+		builder.From(awaitingPayment).OnExit("leave", func(context.Context, paymentPayload) error {
+			exits++
+			return nil
+		})
+		machine := fsm.New(builder.Build(), awaitingPayment)
+
+		/* ---------------------------------- When ---------------------------------- */
+		err := machine.Fire(t.Context(), pay, paymentPayload{})
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.NoError(err)
+		require.Equal(paid, machine.State())
+		require.Equal(3, attempts)
+		require.Equal(1, exits, "exit hooks must not be re-run by retries")
+		require.Equal([]time.Duration{time.Second, 2 * time.Second}, clock.Sleeps())
+		require.Len(events, 3)
+		require.Equal(fsm.PhaseAction, events[0].Step.Phase)
+		require.Equal("charge card", events[0].Step.Description)
+		require.ErrorIs(events[0].Err, errDeclined)
+		require.Equal(time.Second, events[0].Backoff)
+		require.Equal(3, events[2].Attempt)
+		require.NoError(events[2].Err)
+	})
+
+	t.Run("exposes the attempt count when every attempt fails", func(t *testing.T) {
+		require := require.New(t)
+
+		builder := fsm.NewBuilder[paymentState, paymentTrigger, paymentPayload]().WithClock(fsmtest.NewClock(time.Unix(0, 0)))
+		builder.From(awaitingPayment).On(pay).To(paid).
+			Do("charge card", func(context.Context, paymentPayload) error { return errDeclined }).
+			Retry(fsm.RetryPolicy{MaxAttempts: 3})
+		machine := fsm.New(builder.Build(), awaitingPayment)
+
+		err := machine.Fire(t.Context(), pay, paymentPayload{})
+
+		var attemptsErr *fsm.AttemptsError
+		require.ErrorAs(err, &attemptsErr)
+		require.Equal(3, attemptsErr.Attempts)
+		require.ErrorIs(err, errDeclined)
+		require.Equal(awaitingPayment, machine.State())
+	})
+
+	t.Run("stops early when RetryIf rejects the error", func(t *testing.T) {
+		require := require.New(t)
+
+		attempts := 0
+		builder := fsm.NewBuilder[paymentState, paymentTrigger, paymentPayload]().WithClock(fsmtest.NewClock(time.Unix(0, 0)))
+		builder.From(awaitingPayment).On(pay).To(paid).
+			Do("charge card", func(context.Context, paymentPayload) error {
+				attempts++
+				return errDeclined
+			}).
+			Retry(fsm.RetryPolicy{MaxAttempts: 3, RetryIf: func(err error) bool { return !errors.Is(err, errDeclined) }})
+
+		err := fsm.New(builder.Build(), awaitingPayment).Fire(t.Context(), pay, paymentPayload{})
+
+		var attemptsErr *fsm.AttemptsError
+		require.ErrorAs(err, &attemptsErr)
+		require.Equal(1, attemptsErr.Attempts)
+		require.Equal(1, attempts)
+	})
+}
+
+func TestBranchStep_Timeout(t *testing.T) {
+	t.Run("cancels the action's context once the timeout elapses", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		clock := fsmtest.NewClock(time.Unix(0, 0))
+		builder := fsm.NewBuilder[paymentState, paymentTrigger, paymentPayload]().WithClock(clock)
+		builder.From(awaitingPayment).On(pay).To(paid).
+			Do("charge card", func(ctx context.Context, _ paymentPayload) error {
+				// The call takes 3s, simulated on the fake clock.
+				if err := clock.Sleep(ctx, 3*time.Second); err != nil {
+					return err
+				}
+				return ctx.Err()
+			}).
+			Timeout(2 * time.Second)
+		machine := fsm.New(builder.Build(), awaitingPayment)
+
+		/* ---------------------------------- When ---------------------------------- */
+		err := machine.Fire(t.Context(), pay, paymentPayload{})
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.ErrorIs(err, fsm.ErrTimeout)
+		require.ErrorIs(err, context.Canceled)
+		require.Equal(awaitingPayment, machine.State())
+		require.Zero(clock.Pending(), "the timeout timer must be released")
+	})
+
+	t.Run("applies per attempt when combined with Retry", func(t *testing.T) {
+		require := require.New(t)
+
+		clock := fsmtest.NewClock(time.Unix(0, 0))
+		attempts := 0
+		builder := fsm.NewBuilder[paymentState, paymentTrigger, paymentPayload]().WithClock(clock)
+		builder.From(awaitingPayment).On(pay).To(paid).
+			Do("charge card", func(ctx context.Context, _ paymentPayload) error {
+				attempts++
+				if attempts == 1 {
+					clock.Advance(3 * time.Second)
+				}
+				return ctx.Err()
+			}).
+			Timeout(2 * time.Second).
+			Retry(fsm.RetryPolicy{MaxAttempts: 2})
+		machine := fsm.New(builder.Build(), awaitingPayment)
+
+		require.NoError(machine.Fire(t.Context(), pay, paymentPayload{}))
+		require.Equal(2, attempts)
+		require.Equal(paid, machine.State())
+	})
+}
+
+func TestHookStep_TimeoutAndRetry(t *testing.T) {
+	require := require.New(t)
+
+	/* ---------------------------------- Given --------------------------------- */
+	clock := fsmtest.NewClock(time.Unix(0, 0))
+	entries := 0
+	builder := fsm.NewBuilder[paymentState, paymentTrigger, paymentPayload]().WithClock(clock)
+	builder.From(awaitingPayment).On(pay).To(paid)
+	builder.From(paid).
+		OnEntry("send receipt", func(ctx context.Context, _ paymentPayload) error {
+			entries++
+			if entries == 1 {
+				return errors.New("mail server unavailable")
+			}
+			return nil
+		}).
+		Timeout(time.Second).
+		Retry(fsm.RetryPolicy{MaxAttempts: 2, Backoff: func(int) time.Duration { return 500 * time.Millisecond }})
+	machine := fsm.New(builder.Build(), awaitingPayment)
+
+	/* ---------------------------------- When ---------------------------------- */
+	err := machine.Fire(t.Context(), pay, paymentPayload{})
+
+	/* ---------------------------------- Then ---------------------------------- */
+	require.NoError(err)
+	require.Equal(2, entries)
+	require.Equal([]time.Duration{500 * time.Millisecond}, clock.Sleeps())
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := fsm.ExponentialBackoff(100*time.Millisecond, time.Second)
+
+	require.Equal(t, []time.Duration{
+		100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second,
+	}, []time.Duration{backoff(1), backoff(2), backoff(3), backoff(4), backoff(5)})
+}
@@ -0,0 +1,213 @@
+package fsm
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// stateTimeout is an After declaration: fire trigger once the state has been active for after.
+type stateTimeout[T ~uint] struct {
+	after   time.Duration
+	trigger T
+}
+
+// After declares that trigger is fired once the state being defined has been continuously active for d, e.g. to
+// expire an order left in AwaitingPayment. The deadline is armed when the state is entered and cancelled when it is
+// exited, including by a self-transition, which re-arms it. Declarations take effect only for machines driven by a
+// Scheduler.
+func (fs *fromStep[S, T, Payload]) After(d time.Duration, trigger T) *fromStep[S, T, Payload] {
+	sb := &stateBuilder[S, T, Payload]{
+		b:            fs.b,
+		state:        fs.from,
+		timeout:      stateTimeout[T]{after: d, trigger: trigger},
+		isTimeoutSet: true,
+	}
+	fs.b.stateBuilders = append(fs.b.stateBuilders, sb)
+	return fs
+}
+
+// Deadline is a pending state timeout: Trigger is fired at At unless State is exited first.
+type Deadline[S, T ~uint] struct {
+	State   S         `json:"state"`
+	Trigger T         `json:"trigger"`
+	At      time.Time `json:"at"`
+}
+
+// Scheduler drives a machine whose spec declares state timeouts with After. It arms a deadline, on the spec's Clock,
+// whenever a state with After declarations is entered, cancels it when the state is exited, and fires the trigger
+// when it expires.
+//
+// A Scheduler serializes access to its machine, so the machine may be fired concurrently, and by expiring deadlines,
+// as long as every Fire goes through the Scheduler rather than the machine directly.
+type Scheduler[S, T ~uint, Payload any] struct {
+	mu      sync.Mutex
+	machine *Machine[S, T, Payload]
+	clock   Clock
+	payload func(Deadline[S, T]) Payload
+	onError func(ctx context.Context, deadline Deadline[S, T], err error)
+	ctx     context.Context // context for firing expired deadlines; set by Start/Resume
+	armed   []*armedDeadline[S, T]
+	running bool
+}
+
+type armedDeadline[S, T ~uint] struct {
+	Deadline[S, T]
+	timer Timer
+}
+
+// NewScheduler creates a Scheduler for the machine. Call Start or Resume to arm deadlines.
+func NewScheduler[S, T ~uint, Payload any](machine *Machine[S, T, Payload]) *Scheduler[S, T, Payload] {
+	s := &Scheduler[S, T, Payload]{machine: machine, clock: machine.spec.clock}
+	machine.Observe(Observer[S, T, Payload]{OnTransition: s.onTransition})
+	return s
+}
+
+// WithPayload sets the function providing the payload an expired deadline's trigger is fired with. Without it, the
+// zero Payload is used.
+func (s *Scheduler[S, T, Payload]) WithPayload(payload func(Deadline[S, T]) Payload) *Scheduler[S, T, Payload] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.payload = payload
+	return s
+}
+
+// OnError sets the function called when firing an expired deadline's trigger fails. Without it, such errors are
+// dropped.
+func (s *Scheduler[S, T, Payload]) OnError(
+	onError func(ctx context.Context, deadline Deadline[S, T], err error),
+) *Scheduler[S, T, Payload] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onError = onError
+	return s
+}
+
+// Start arms the deadlines of every state in the machine's active hierarchy, measured from now, as if the states had
+// just been entered. Expired deadlines are fired with ctx.
+func (s *Scheduler[S, T, Payload]) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ctx, s.running = ctx, true
+	hierarchy := s.machine.ActiveHierarchy()
+	slices.Reverse(hierarchy)
+	for _, st := range hierarchy {
+		s.armState(st)
+	}
+}
+
+// Resume arms previously snapshotted deadlines instead of computing fresh ones, so timeouts survive a restart.
+// Deadlines already in the past expire immediately. Expired deadlines are fired with ctx.
+func (s *Scheduler[S, T, Payload]) Resume(ctx context.Context, deadlines []Deadline[S, T]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ctx, s.running = ctx, true
+	for _, d := range deadlines {
+		s.arm(d)
+	}
+}
+
+// Stop cancels every pending deadline. The machine can still be fired, but no deadlines are armed until Start or
+// Resume is called again.
+func (s *Scheduler[S, T, Payload]) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range s.armed {
+		a.timer.Stop()
+	}
+	s.armed, s.running = nil, false
+}
+
+// Fire fires the trigger on the machine, re-arming deadlines for the states exited and entered.
+func (s *Scheduler[S, T, Payload]) Fire(ctx context.Context, trigger T, payload Payload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.machine.Fire(ctx, trigger, payload)
+}
+
+// State returns the machine's current state.
+func (s *Scheduler[S, T, Payload]) State() S {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.machine.State()
+}
+
+// Deadlines returns the pending deadlines, ordered by expiry.
+func (s *Scheduler[S, T, Payload]) Deadlines() []Deadline[S, T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deadlines()
+}
+
+// Snapshot returns the machine's snapshot including its pending deadlines.
+func (s *Scheduler[S, T, Payload]) Snapshot() Snapshot[S, T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := s.machine.Snapshot()
+	snapshot.Deadlines = s.deadlines()
+	return snapshot
+}
+
+func (s *Scheduler[S, T, Payload]) deadlines() []Deadline[S, T] {
+	deadlines := make([]Deadline[S, T], 0, len(s.armed))
+	for _, a := range s.armed {
+		deadlines = append(deadlines, a.Deadline)
+	}
+	slices.SortStableFunc(deadlines, func(a, b Deadline[S, T]) int { return a.At.Compare(b.At) })
+	return deadlines
+}
+
+// onTransition re-arms deadlines after a transition. It runs inside machine.Fire, which the Scheduler only calls with
+// s.mu held.
+func (s *Scheduler[S, T, Payload]) onTransition(_ context.Context, event TransitionEvent[S, T, Payload]) {
+	if !s.running {
+		return
+	}
+	s.armed = slices.DeleteFunc(s.armed, func(a *armedDeadline[S, T]) bool {
+		if slices.Contains(event.Exited, a.State) {
+			a.timer.Stop()
+			return true
+		}
+		return false
+	})
+	for _, st := range event.Entered {
+		s.armState(st)
+	}
+}
+
+// armState arms the deadlines declared for the state, measured from now. s.mu must be held.
+func (s *Scheduler[S, T, Payload]) armState(state S) {
+	if uint(state) >= uint(len(s.machine.spec.stateTimeouts)) {
+		return
+	}
+	now := s.clock.Now()
+	for _, timeout := range s.machine.spec.stateTimeouts[state] {
+		s.arm(Deadline[S, T]{State: state, Trigger: timeout.trigger, At: now.Add(timeout.after)})
+	}
+}
+
+// arm schedules the deadline. s.mu must be held.
+func (s *Scheduler[S, T, Payload]) arm(deadline Deadline[S, T]) {
+	a := &armedDeadline[S, T]{Deadline: deadline}
+	a.timer = s.clock.AfterFunc(deadline.At.Sub(s.clock.Now()), func() { s.expire(a) })
+	s.armed = append(s.armed, a)
+}
+
+// expire fires an armed deadline's trigger unless it was cancelled in the meantime.
+func (s *Scheduler[S, T, Payload]) expire(a *armedDeadline[S, T]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx := slices.Index(s.armed, a)
+	if idx < 0 {
+		return
+	}
+	s.armed = slices.Delete(s.armed, idx, idx+1)
+	var payload Payload
+	if s.payload != nil {
+		payload = s.payload(a.Deadline)
+	}
+	if err := s.machine.Fire(s.ctx, a.Trigger, payload); err != nil && s.onError != nil {
+		s.onError(s.ctx, a.Deadline, err)
+	}
+}
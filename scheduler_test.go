@@ -0,0 +1,165 @@
+package fsm_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tobbstr/fsm"
+	"github.com/tobbstr/fsm/fsmtest"
+)
+
+type orderState uint
+
+const (
+	awaitingOrderPayment orderState = iota
+	orderPaid
+	orderExpired
+	orderOpen // composite parent of awaitingOrderPayment and orderPaid
+)
+
+type orderTrigger uint
+
+const (
+	payOrder orderTrigger = iota
+	expireOrder
+	remind
+	closeOrder
+)
+
+type orderPayload struct{ reason string }
+
+func newOrderSpec(clock fsm.Clock) *fsm.Spec[orderState, orderTrigger, orderPayload] {
+	builder := fsm.NewBuilder[orderState, orderTrigger, orderPayload]().WithClock(clock)
+	builder.From(awaitingOrderPayment).WithParent(orderOpen).
+		After(30*time.Minute, expireOrder).
+		On(payOrder).To(orderPaid)
+	builder.From(awaitingOrderPayment).On(expireOrder).To(orderExpired)
+	builder.From(awaitingOrderPayment).On(remind).To(awaitingOrderPayment)
+	builder.From(orderPaid).WithParent(orderOpen)
+	builder.From(orderOpen).After(24*time.Hour, closeOrder).On(closeOrder).To(orderExpired)
+	return builder.Build()
+}
+
+func TestScheduler(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("fires the trigger once the state has been active for the declared duration", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		clock := fsmtest.NewClock(start)
+		var payloads []orderPayload
+		scheduler := fsm.NewScheduler(fsm.New(newOrderSpec(clock), awaitingOrderPayment)).
+			WithPayload(func(d fsm.Deadline[orderState, orderTrigger]) orderPayload {
+				payloads = append(payloads, orderPayload{reason: "timeout"})
+				return payloads[len(payloads)-1]
+			})
+		scheduler.Start(t.Context())
+
+		/* ---------------------------------- When ---------------------------------- */
+		clock.Advance(29 * time.Minute)
+		before := scheduler.State()
+		clock.Advance(time.Minute)
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.Equal(awaitingOrderPayment, before)
+		require.Equal(orderExpired, scheduler.State())
+		require.Len(payloads, 1)
+		require.Empty(scheduler.Deadlines(), "exiting orderOpen must cancel its deadline too")
+	})
+
+	t.Run("exiting the state cancels its deadline", func(t *testing.T) {
+		require := require.New(t)
+
+		clock := fsmtest.NewClock(start)
+		scheduler := fsm.NewScheduler(fsm.New(newOrderSpec(clock), awaitingOrderPayment))
+		scheduler.Start(t.Context())
+
+		require.NoError(scheduler.Fire(t.Context(), payOrder, orderPayload{}))
+		clock.Advance(time.Hour)
+
+		require.Equal(orderPaid, scheduler.State())
+		require.Equal([]fsm.Deadline[orderState, orderTrigger]{
+			{State: orderOpen, Trigger: closeOrder, At: start.Add(24 * time.Hour)},
+		}, scheduler.Deadlines(), "the parent's deadline survives a transition within it")
+	})
+
+	t.Run("a self-transition re-arms the deadline", func(t *testing.T) {
+		require := require.New(t)
+
+		clock := fsmtest.NewClock(start)
+		scheduler := fsm.NewScheduler(fsm.New(newOrderSpec(clock), awaitingOrderPayment))
+		scheduler.Start(t.Context())
+
+		clock.Advance(20 * time.Minute)
+		require.NoError(scheduler.Fire(t.Context(), remind, orderPayload{}))
+		clock.Advance(20 * time.Minute)
+
+		require.Equal(awaitingOrderPayment, scheduler.State())
+		require.Contains(scheduler.Deadlines(), fsm.Deadline[orderState, orderTrigger]{
+			State: awaitingOrderPayment, Trigger: expireOrder, At: start.Add(50 * time.Minute),
+		})
+	})
+
+	t.Run("stop cancels every pending deadline", func(t *testing.T) {
+		require := require.New(t)
+
+		clock := fsmtest.NewClock(start)
+		scheduler := fsm.NewScheduler(fsm.New(newOrderSpec(clock), awaitingOrderPayment))
+		scheduler.Start(t.Context())
+
+		scheduler.Stop()
+		clock.Advance(48 * time.Hour)
+
+		require.Equal(awaitingOrderPayment, scheduler.State())
+		require.Zero(clock.Pending())
+	})
+
+	t.Run("reports errors from firing an expired deadline", func(t *testing.T) {
+		require := require.New(t)
+
+		clock := fsmtest.NewClock(start)
+		builder := fsm.NewBuilder[orderState, orderTrigger, orderPayload]().WithClock(clock)
+		builder.From(awaitingOrderPayment).After(time.Minute, expireOrder) // no transition for expireOrder
+		var gotErr error
+		scheduler := fsm.NewScheduler(fsm.New(builder.Build(), awaitingOrderPayment)).
+			OnError(func(ctx context.Context, d fsm.Deadline[orderState, orderTrigger], err error) { gotErr = err })
+		scheduler.Start(t.Context())
+
+		clock.Advance(time.Minute)
+
+		require.ErrorIs(gotErr, fsm.ErrNotFound)
+	})
+}
+
+func TestScheduler_SnapshotAndResume(t *testing.T) {
+	require := require.New(t)
+
+	/* ---------------------------------- Given --------------------------------- */
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := fsmtest.NewClock(start)
+	scheduler := fsm.NewScheduler(fsm.New(newOrderSpec(clock), awaitingOrderPayment))
+	scheduler.Start(t.Context())
+	clock.Advance(10 * time.Minute)
+
+	data, err := json.Marshal(scheduler.Snapshot())
+	require.NoError(err)
+	scheduler.Stop()
+
+	/* ---------------------------------- When ---------------------------------- */
+	// Simulate a restart: a new process with its own clock restores the snapshot.
+	restartedClock := fsmtest.NewClock(start.Add(15 * time.Minute))
+	var snapshot fsm.Snapshot[orderState, orderTrigger]
+	require.NoError(json.Unmarshal(data, &snapshot))
+	restored := fsm.NewScheduler(fsm.Restore(newOrderSpec(restartedClock), snapshot))
+	restored.Resume(t.Context(), snapshot.Deadlines)
+	restartedClock.Advance(15 * time.Minute)
+
+	/* ---------------------------------- Then ---------------------------------- */
+	require.Equal(awaitingOrderPayment, snapshot.State)
+	require.Len(snapshot.Deadlines, 2)
+	require.Equal(orderExpired, restored.State(), "the deadline must keep its original expiry across the restart")
+}
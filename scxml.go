@@ -0,0 +1,286 @@
+package fsm
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+type scxmlDocument struct {
+	XMLName   xml.Name        `xml:"http://www.w3.org/2005/07/scxml scxml"`
+	Version   string          `xml:"version,attr"`
+	Datamodel string          `xml:"datamodel,attr,omitempty"`
+	States    []scxmlState    `xml:"state"`
+	Finals    []scxmlState    `xml:"final"`
+	Parallels []scxmlParallel `xml:"parallel"`
+}
+
+type scxmlState struct {
+	ID          string            `xml:"id,attr"`
+	Initial     string            `xml:"initial,attr,omitempty"`
+	InitialElem *scxmlInitial     `xml:"initial"`
+	OnEntry     []scxmlExecutable `xml:"onentry"`
+	OnExit      []scxmlExecutable `xml:"onexit"`
+	Transitions []scxmlTransition `xml:"transition"`
+	States      []scxmlState      `xml:"state"`
+	Finals      []scxmlState      `xml:"final"`
+	Parallels   []scxmlParallel   `xml:"parallel"`
+}
+
+type scxmlInitial struct {
+	Transition scxmlTransition `xml:"transition"`
+}
+
+type scxmlParallel struct {
+	ID string `xml:"id,attr"`
+}
+
+// scxmlExecutable is an <onentry> or <onexit> block. The <action> and <forbid> extension elements, which name actions
+// and carry the reason of forbidden triggers, are in the https://github.com/tobbstr/fsm namespace, which SCXML
+// processors ignore.
+type scxmlExecutable struct {
+	Actions []scxmlAction `xml:"https://github.com/tobbstr/fsm action"`
+}
+
+type scxmlTransition struct {
+	Event   string        `xml:"event,attr,omitempty"`
+	Cond    string        `xml:"cond,attr,omitempty"`
+	Target  string        `xml:"target,attr,omitempty"`
+	Actions []scxmlAction `xml:"https://github.com/tobbstr/fsm action"`
+	Forbid  *scxmlForbid  `xml:"https://github.com/tobbstr/fsm forbid"`
+}
+
+type scxmlAction struct {
+	Name string `xml:"name,attr"`
+}
+
+type scxmlForbid struct {
+	Reason string `xml:"reason,attr"`
+}
+
+// SCXML returns the Spec as a W3C SCXML document. States and events are identified by their %v formatting, composite
+// states are nested with their initial substate in the initial attribute, and branches become transitions in
+// definition order, which SCXML also evaluates first-match-wins. Conditions are written as cond expressions, and
+// actions and hooks as <action name="..."/> elements in the fsm namespace, all by their descriptions; undescribed ones
+// cannot be referred to and make SCXML fail. Ignored triggers become targetless transitions and forbidden ones carry a
+// <forbid reason="..."/> element. State timeouts and OnUnhandled handlers are not exported.
+func (spec *Spec[S, T, Payload]) SCXML() ([]byte, error) {
+	d := spec.fullDiagram(DiagramOptions{})
+	var errs []error
+	var element func(state S) scxmlState
+	element = func(state S) scxmlState {
+		el := scxmlState{ID: fmtName(state)}
+		if initial, ok := d.initial[state]; ok {
+			el.Initial = fmtName(initial)
+		}
+		hooks := spec.stateHooks[state]
+		if actions := spec.scxmlHookActions(state, "entry", hooks.onEntry, &errs); len(actions) > 0 {
+			el.OnEntry = []scxmlExecutable{{Actions: actions}}
+		}
+		if actions := spec.scxmlHookActions(state, "exit", hooks.onExit, &errs); len(actions) > 0 {
+			el.OnExit = []scxmlExecutable{{Actions: actions}}
+		}
+		for trigger := uint(0); trigger < spec.triggerCount; trigger++ {
+			s := &spec.slots[transitionIndex(state, T(trigger), spec.triggerCount)]
+			event := fmtName(T(trigger))
+			switch s.disposition {
+			case ignored:
+				el.Transitions = append(el.Transitions, scxmlTransition{Event: event})
+			case forbidden:
+				el.Transitions = append(el.Transitions, scxmlTransition{Event: event, Forbid: &scxmlForbid{Reason: s.reason}})
+			}
+			if !s.valid {
+				continue
+			}
+			for _, br := range s.all() {
+				if br.cond != nil && br.condDesc == "" {
+					errs = append(errs, fmt.Errorf("state %v: condition of transition on %s to %v has no description", state, event, br.next))
+				}
+				t := scxmlTransition{Event: event, Cond: br.condDesc, Target: fmtName(br.next)}
+				if br.action != nil {
+					if br.actionDesc == "" {
+						errs = append(errs, fmt.Errorf("state %v: action of transition on %s to %v has no description", state, event, br.next))
+					}
+					t.Actions = []scxmlAction{{Name: br.actionDesc}}
+				}
+				el.Transitions = append(el.Transitions, t)
+			}
+		}
+		for _, child := range d.children[state] {
+			el.States = append(el.States, element(child))
+		}
+		return el
+	}
+	doc := scxmlDocument{Version: "1.0", Datamodel: "null"}
+	for _, root := range d.roots {
+		doc.States = append(doc.States, element(root))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding SCXML: %w", err)
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// scxmlHookActions returns the <action> elements of a state's entry or exit hooks, recording an error for each
+// undescribed hook.
+func (spec *Spec[S, T, Payload]) scxmlHookActions(state S, kind string, hooks []hook[Payload], errs *[]error) []scxmlAction {
+	var actions []scxmlAction
+	for _, h := range hooks {
+		if h.desc == "" {
+			*errs = append(*errs, fmt.Errorf("state %v: %s hook has no description", state, kind))
+		}
+		actions = append(actions, scxmlAction{Name: h.desc})
+	}
+	return actions
+}
+
+// ReadSCXML builds a Builder from an SCXML document such as one written by Spec.SCXML. State IDs, events, cond
+// expressions and action names are resolved through registry; cond expressions are not evaluated, but name a
+// Condition. <final> states are read as states. Parallel states, eventless transitions, multiple events or targets
+// per transition, and targetless transitions with actions have no equivalent and are reported.
+//
+// Every unresolved name (wrapping ErrUnresolvedName), unsupported construct and invalid declaration, such as an
+// unconditional transition before a conditional one, an initial state that is not a child, a state declared twice or
+// nesting deeper than a spec supports, is reported at once, joined. The returned Builder can be extended, e.g. with
+// timeouts and observers, before Build.
+func ReadSCXML[S, T ~uint, Payload any](r io.Reader, registry Registry[S, T, Payload]) (*Builder[S, T, Payload], error) {
+	var doc scxmlDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decoding SCXML: %w", err)
+	}
+	b := NewBuilder[S, T, Payload]()
+	res := &resolver[S, T, Payload]{registry: &registry}
+	for _, p := range doc.Parallels {
+		res.fail(fmt.Sprintf("parallel %q", p.ID), "parallel states are not supported")
+	}
+	declared := make(map[string]bool)
+	for _, el := range append(doc.States, doc.Finals...) {
+		readSCXMLState(b, res, declared, el, nil, 1)
+	}
+	if err := res.err(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// readSCXMLState declares the state el, nested at depth in parent if it is not nil, and its substates on b. declared
+// holds the IDs of the states read so far.
+func readSCXMLState[S, T ~uint, Payload any](
+	b *Builder[S, T, Payload], res *resolver[S, T, Payload], declared map[string]bool, el scxmlState, parent *S, depth int,
+) {
+	where := fmt.Sprintf("state %q", el.ID)
+	if declared[el.ID] {
+		res.fail(where, "the state is declared more than once")
+		return
+	}
+	declared[el.ID] = true
+	if depth > maxDepth {
+		res.fail(where, "states are nested deeper than the maximum of %d levels", maxDepth)
+		return
+	}
+	state := res.state(where, el.ID)
+	if parent != nil {
+		b.From(state).WithParent(*parent)
+	}
+	children := append(el.States, el.Finals...)
+	initial := el.Initial
+	if el.InitialElem != nil {
+		initial = el.InitialElem.Transition.Target
+	}
+	if initial != "" {
+		if slices.ContainsFunc(children, func(child scxmlState) bool { return child.ID == initial }) {
+			b.From(state).WithInitial(res.state(where, initial))
+		} else {
+			res.fail(where, "initial state %q is not a child state", initial)
+		}
+	}
+	for _, exec := range el.OnEntry {
+		for _, a := range exec.Actions {
+			b.From(state).OnEntry(a.Name, res.action(where, a.Name))
+		}
+	}
+	for _, exec := range el.OnExit {
+		for _, a := range exec.Actions {
+			b.From(state).OnExit(a.Name, res.action(where, a.Name))
+		}
+	}
+
+	// Group the transitions by event, keeping document order within each event.
+	var events []string
+	byEvent := make(map[string][]scxmlTransition)
+	for _, t := range el.Transitions {
+		if t.Event == "" || strings.ContainsAny(t.Event, " \t\n*") {
+			res.fail(where, "transition event %q is not a single event", t.Event)
+			continue
+		}
+		if _, ok := byEvent[t.Event]; !ok {
+			events = append(events, t.Event)
+		}
+		byEvent[t.Event] = append(byEvent[t.Event], t)
+	}
+	for _, event := range events {
+		readSCXMLTransitions(b, res, where, state, res.trigger(where, event), byEvent[event])
+	}
+
+	for _, p := range el.Parallels {
+		res.fail(fmt.Sprintf("parallel %q", p.ID), "parallel states are not supported")
+	}
+	for _, child := range children {
+		readSCXMLState(b, res, declared, child, &state, depth+1)
+	}
+}
+
+// readSCXMLTransitions declares the transitions of state on one trigger: an Ignore or Forbid for a single targetless
+// transition, else one branch per transition.
+func readSCXMLTransitions[S, T ~uint, Payload any](
+	b *Builder[S, T, Payload], res *resolver[S, T, Payload], where string, state S, trigger T, transitions []scxmlTransition,
+) {
+	where = fmt.Sprintf("%s: transition on %q", where, transitions[0].Event)
+	if transitions[0].Target == "" {
+		t := transitions[0]
+		switch {
+		case len(transitions) > 1:
+			res.fail(where, "a targetless transition must be the only one for its event")
+		case len(t.Actions) > 0 || t.Cond != "":
+			res.fail(where, "targetless transitions with a cond or actions are not supported")
+		case t.Forbid != nil:
+			b.From(state).Forbid(trigger, t.Forbid.Reason)
+		default:
+			b.From(state).Ignore(trigger)
+		}
+		return
+	}
+	var bs *branchStep[S, T, Payload]
+	for i, t := range transitions {
+		if t.Cond == "" && i < len(transitions)-1 {
+			res.fail(where, "an unconditional transition must be the last one for its event")
+		}
+		if t.Target == "" || strings.ContainsAny(t.Target, " \t\n") {
+			res.fail(where, "transition target %q is not a single state", t.Target)
+			continue
+		}
+		to := res.state(where, t.Target)
+		if bs == nil {
+			bs = b.From(state).On(trigger).To(to)
+		} else {
+			bs = bs.To(to)
+		}
+		if t.Cond != "" {
+			bs.When(t.Cond, res.condition(where, t.Cond))
+		}
+		switch len(t.Actions) {
+		case 0:
+		case 1:
+			bs.Do(t.Actions[0].Name, res.action(where, t.Actions[0].Name))
+		default:
+			res.fail(where, "a transition can have at most one action, got %d", len(t.Actions))
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package fsm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newPlayerRegistry() Registry[playerState, playerTrigger, payload] {
+	noop := func(context.Context, payload) error { return nil }
+	return Registry[playerState, playerTrigger, payload]{
+		States:     Names(stopped, active, playing, paused, expired),
+		Triggers:   Names(play, pause, stop),
+		Conditions: map[string]Condition[payload]{`license "valid"`: func(payload) bool { return true }},
+		Actions: map[string]Action[payload]{
+			"load track": noop, "release device": noop, "start audio": noop, "stop audio": noop,
+		},
+	}
+}
+
+func TestSpec_SCXML(t *testing.T) {
+	t.Run("exports states, nesting, transitions and hooks", func(t *testing.T) {
+		data, err := newPlayerSpec().SCXML()
+
+		require.NoError(t, err)
+		requireGolden(t, "player.scxml", string(data))
+	})
+
+	t.Run("round-trips through ReadSCXML", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		exported, err := newPlayerSpec().SCXML()
+		require.NoError(err)
+
+		/* ---------------------------------- When ---------------------------------- */
+		builder, err := ReadSCXML(bytes.NewReader(exported), newPlayerRegistry())
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.NoError(err)
+		spec := builder.Build()
+		reexported, err := spec.SCXML()
+		require.NoError(err)
+		require.Equal(string(exported), string(reexported))
+		require.Equal(newPlayerSpec().MermaidJSDiagram(), spec.MermaidJSDiagram())
+
+		machine := New(spec, stopped)
+		require.NoError(machine.Fire(t.Context(), play, payload{}))
+		require.Equal([]playerState{playing, active}, machine.ActiveHierarchy())
+		require.NoError(machine.Fire(t.Context(), stop, payload{}))
+		require.Equal(stopped, machine.State())
+	})
+
+	t.Run("round-trips ignored and forbidden triggers", func(t *testing.T) {
+		require := require.New(t)
+
+		builder := NewBuilder[state, trigger, payload]()
+		builder.From(locked).On(unlock).To(unlocked)
+		builder.From(locked).Ignore(lock)
+		builder.From(unlocked).Forbid(unlock, "already open")
+		exported, err := builder.Build().SCXML()
+		require.NoError(err)
+		require.Contains(string(exported), `<transition event="lock"></transition>`)
+		require.Contains(string(exported), `<forbid xmlns="https://github.com/tobbstr/fsm" reason="already open"></forbid>`)
+
+		imported, err := ReadSCXML(bytes.NewReader(exported), Registry[state, trigger, payload]{
+			States: Names(locked, unlocked), Triggers: Names(unlock, lock),
+		})
+		require.NoError(err)
+		machine := New(imported.Build(), locked)
+
+		require.NoError(machine.Fire(t.Context(), lock, payload{}))
+		require.NoError(machine.Fire(t.Context(), unlock, payload{}))
+		require.ErrorIs(machine.Fire(t.Context(), unlock, payload{}), ErrTransitionForbidden)
+	})
+
+	t.Run("fails for undescribed conditions, actions and hooks", func(t *testing.T) {
+		require := require.New(t)
+
+		noop := func(context.Context, payload) error { return nil }
+		builder := NewBuilder[state, trigger, payload]()
+		builder.From(locked).On(unlock).To(unlocked).When("", func(payload) bool { return true }).Do("", noop)
+		builder.From(unlocked).WithHooks(StateHooks[payload]{OnEntry: noop})
+
+		_, err := builder.Build().SCXML()
+
+		require.ErrorContains(err, "state locked: condition of transition on unlock to unlocked has no description")
+		require.ErrorContains(err, "state locked: action of transition on unlock to unlocked has no description")
+		require.ErrorContains(err, "state unlocked: entry hook has no description")
+	})
+}
+
+func TestReadSCXML(t *testing.T) {
+	t.Run("reads initial elements and final states", func(t *testing.T) {
+		require := require.New(t)
+
+		doc := `<scxml xmlns="http://www.w3.org/2005/07/scxml" version="1.0">
+			<state id="Active">
+				<initial><transition target="Paused"/></initial>
+				<state id="Playing"><transition event="Pause" target="Paused"/></state>
+				<state id="Paused"><transition event="Play" target="Playing"/></state>
+				<transition event="Stop" target="Stopped"/>
+			</state>
+			<final id="Stopped"/>
+		</scxml>`
+
+		builder, err := ReadSCXML(strings.NewReader(doc), newPlayerRegistry())
+
+		require.NoError(err)
+		machine := New(builder.Build(), stopped)
+		require.ErrorIs(machine.Fire(t.Context(), play, payload{}), ErrNotFound)
+		machine = New(builder.Build(), playing)
+		require.NoError(machine.Fire(t.Context(), stop, payload{}))
+		require.Equal(stopped, machine.State())
+	})
+
+	t.Run("reports every unresolved name and unsupported construct", func(t *testing.T) {
+		require := require.New(t)
+
+		doc := `<scxml xmlns="http://www.w3.org/2005/07/scxml" version="1.0">
+			<state id="Stopped">
+				<transition event="Play" cond="has license" target="Rewinding"><action xmlns="https://github.com/tobbstr/fsm" name="beep"/></transition>
+				<transition event="Eject" target="Stopped"/>
+				<transition target="Stopped"/>
+			</state>
+			<parallel id="Both"/>
+		</scxml>`
+
+		_, err := ReadSCXML(strings.NewReader(doc), newPlayerRegistry())
+
+		require.ErrorIs(err, ErrUnresolvedName)
+		for _, msg := range []string{
+			`state "Stopped": transition on "Play": unresolved name: state "Rewinding"`,
+			`state "Stopped": transition on "Play": unresolved name: condition "has license"`,
+			`state "Stopped": transition on "Play": unresolved name: action "beep"`,
+			`state "Stopped": unresolved name: trigger "Eject"`,
+			`state "Stopped": transition event "" is not a single event`,
+			`parallel "Both": parallel states are not supported`,
+		} {
+			require.ErrorContains(err, msg)
+		}
+	})
+
+	t.Run("reports declarations Build would reject", func(t *testing.T) {
+		require := require.New(t)
+
+		doc := `<scxml xmlns="http://www.w3.org/2005/07/scxml" version="1.0">
+			<state id="Stopped">
+				<transition event="Play" target="Playing"/>
+				<transition event="Play" cond="license &quot;valid&quot;" target="Expired"/>
+			</state>
+			<state id="Active" initial="Stopped"><state id="Playing"/></state>
+			<state id="Expired"><state id="Expired"/></state>
+		</scxml>`
+
+		_, err := ReadSCXML(strings.NewReader(doc), newPlayerRegistry())
+
+		for _, msg := range []string{
+			`state "Stopped": transition on "Play": an unconditional transition must be the last one for its event`,
+			`state "Active": initial state "Stopped" is not a child state`,
+			`state "Expired": the state is declared more than once`,
+		} {
+			require.ErrorContains(err, msg)
+		}
+	})
+
+	t.Run("reports nesting deeper than a spec supports", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		registry := Registry[playerState, playerTrigger, payload]{States: map[string]playerState{}}
+		var doc strings.Builder
+		doc.WriteString(`<scxml xmlns="http://www.w3.org/2005/07/scxml" version="1.0">`)
+		for i := range maxDepth + 1 {
+			id := fmt.Sprint("level-", i+1)
+			registry.States[id] = playerState(i)
+			fmt.Fprintf(&doc, `<state id="%s">`, id)
+		}
+		doc.WriteString(strings.Repeat(`</state>`, maxDepth+1) + `</scxml>`)
+
+		/* ---------------------------------- When ---------------------------------- */
+		builder, err := ReadSCXML(strings.NewReader(doc.String()), registry)
+
+		/* ---------------------------------- Then ---------------------------------- */
+		require.Nil(builder)
+		require.EqualError(err, fmt.Sprintf(
+			`state "level-%d": states are nested deeper than the maximum of %d levels`, maxDepth+1, maxDepth))
+	})
+
+	t.Run("rejects documents outside the SCXML namespace", func(t *testing.T) {
+		_, err := ReadSCXML(strings.NewReader(`<scxml version="1.0"/>`), newPlayerRegistry())
+
+		require.ErrorContains(t, err, "decoding SCXML: expected element <scxml> in name space http://www.w3.org/2005/07/scxml")
+	})
+}
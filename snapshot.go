@@ -0,0 +1,28 @@
+package fsm
+
+// Snapshot is the serializable runtime state of a machine, used to persist it and restore it later, e.g. after a
+// restart. It does not include the spec, which is code.
+type Snapshot[S, T ~uint] struct {
+	State     S                `json:"state"`
+	Deadlines []Deadline[S, T] `json:"deadlines,omitempty"` // pending state timeouts; set by Scheduler.Snapshot
+	Seq       uint64           `json:"seq,omitempty"`       // last event applied; set by SourcedMachine.Snapshot
+	Entered   []StateEntry[S]  `json:"entered,omitempty"`   // active hierarchy's entry times; set when tracking time in state
+}
+
+// Snapshot returns the machine's current runtime state.
+func (m *Machine[S, T, Payload]) Snapshot() Snapshot[S, T] {
+	return Snapshot[S, T]{State: m.state, Entered: m.enteredEntries()}
+}
+
+// Restore creates a machine from the spec in the state recorded by the snapshot. If the snapshot has entry times, the
+// machine tracks time in state from them. Pending deadlines are restored separately with Scheduler.Resume.
+func Restore[S, T ~uint, Payload any](spec *Spec[S, T, Payload], snapshot Snapshot[S, T]) *Machine[S, T, Payload] {
+	m := New(spec, snapshot.State)
+	if len(snapshot.Entered) > 0 {
+		m.TrackTimeInState()
+		for _, e := range snapshot.Entered {
+			m.setEntered(e.State, e.At)
+		}
+	}
+	return m
+}
@@ -0,0 +1,135 @@
+package fsm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// MemoryStore is an in-memory Store, for tests and single-process use.
+type MemoryStore[ID comparable, S, T ~uint] struct {
+	mu      sync.Mutex
+	records map[ID]Record[S, T]
+}
+
+var _ Store[string, uint, uint] = (*MemoryStore[string, uint, uint])(nil)
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore[ID comparable, S, T ~uint]() *MemoryStore[ID, S, T] {
+	return &MemoryStore[ID, S, T]{records: make(map[ID]Record[S, T])}
+}
+
+// Load returns the machine's record.
+func (s *MemoryStore[ID, S, T]) Load(_ context.Context, id ID) (Record[S, T], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return loadRecord(s.records, id)
+}
+
+// Save stores the snapshot if the stored version is expected.
+func (s *MemoryStore[ID, S, T]) Save(_ context.Context, id ID, snapshot Snapshot[S, T], expected uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return saveRecord(s.records, id, snapshot, expected)
+}
+
+// FileStore is a Store backed by a JSON file, for local use. Every save rewrites the whole file atomically, so it suits
+// modest numbers of machines. ID must be JSON-serializable. It is safe for concurrent use within one process; separate
+// processes must not share the file.
+type FileStore[ID comparable, S, T ~uint] struct {
+	mu   sync.Mutex
+	path string
+}
+
+var _ Store[string, uint, uint] = (*FileStore[string, uint, uint])(nil)
+
+// NewFileStore creates a FileStore persisting to path. The file is created on the first Save.
+func NewFileStore[ID comparable, S, T ~uint](path string) *FileStore[ID, S, T] {
+	return &FileStore[ID, S, T]{path: path}
+}
+
+// fileRecord is a Record as stored in a FileStore's file.
+type fileRecord[ID comparable, S, T ~uint] struct {
+	ID ID `json:"id"`
+	Record[S, T]
+}
+
+// Load returns the machine's record.
+func (s *FileStore[ID, S, T]) Load(_ context.Context, id ID) (Record[S, T], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, err := s.read()
+	if err != nil {
+		return Record[S, T]{}, err
+	}
+	return loadRecord(records, id)
+}
+
+// Save stores the snapshot if the stored version is expected.
+func (s *FileStore[ID, S, T]) Save(_ context.Context, id ID, snapshot Snapshot[S, T], expected uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, err := s.read()
+	if err != nil {
+		return err
+	}
+	if err := saveRecord(records, id, snapshot, expected); err != nil {
+		return err
+	}
+	return s.write(records)
+}
+
+func (s *FileStore[ID, S, T]) read() (map[ID]Record[S, T], error) {
+	records := make(map[ID]Record[S, T])
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading store file: %w", err)
+	}
+	var list []fileRecord[ID, S, T]
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("decoding store file %s: %w", s.path, err)
+	}
+	for _, r := range list {
+		records[r.ID] = r.Record
+	}
+	return records, nil
+}
+
+func (s *FileStore[ID, S, T]) write(records map[ID]Record[S, T]) error {
+	list := make([]fileRecord[ID, S, T], 0, len(records))
+	for id, r := range records {
+		list = append(list, fileRecord[ID, S, T]{ID: id, Record: r})
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding store: %w", err)
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("writing store file: %w", err)
+	}
+	return nil
+}
+
+func loadRecord[ID comparable, S, T ~uint](records map[ID]Record[S, T], id ID) (Record[S, T], error) {
+	record, ok := records[id]
+	if !ok {
+		return record, ErrMachineNotFound
+	}
+	return record, nil
+}
+
+// saveRecord applies the Store.Save contract to records.
+func saveRecord[ID comparable, S, T ~uint](records map[ID]Record[S, T], id ID, snapshot Snapshot[S, T], expected uint64) error {
+	if current := records[id].Version; current != expected {
+		return fmt.Errorf("expected version %d, stored version is %d: %w", expected, current, ErrVersionConflict)
+	}
+	records[id] = Record[S, T]{Snapshot: snapshot, Version: expected + 1}
+	return nil
+}
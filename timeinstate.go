@@ -0,0 +1,172 @@
+package fsm
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// StateEntry records when a state was entered.
+type StateEntry[S ~uint] struct {
+	State S         `json:"state"`
+	At    time.Time `json:"at"`
+}
+
+// entryTimes holds the entry time of every state, indexed by state. Only the times of active states are meaningful.
+type entryTimes []time.Time
+
+// TrackTimeInState makes the machine record when each state of its active hierarchy was entered, on the spec's Clock,
+// so that EnteredAt and TimeInState can report it and transition events carry dwell times. The states active when it is
+// called are treated as entered now. Calling it again has no effect.
+func (m *Machine[S, T, Payload]) TrackTimeInState() *Machine[S, T, Payload] {
+	if m.entered != nil {
+		return m
+	}
+	m.entered = make(entryTimes, m.spec.stateCount)
+	now := m.spec.clock.Now()
+	for _, st := range m.ActiveHierarchy() {
+		m.setEntered(st, now)
+	}
+	return m
+}
+
+// EnteredAt returns when the state was entered, if it is in the active hierarchy and the machine tracks time in state.
+func (m *Machine[S, T, Payload]) EnteredAt(state S) (time.Time, bool) {
+	if m.entered == nil || uint(state) >= uint(len(m.entered)) || !m.IsIn(state) {
+		return time.Time{}, false
+	}
+	return m.entered[state], true
+}
+
+// TimeInState returns how long the state has been active, on the spec's Clock. It returns 0 if the state is not in the
+// active hierarchy or the machine does not track time in state.
+func (m *Machine[S, T, Payload]) TimeInState(state S) time.Duration {
+	at, ok := m.EnteredAt(state)
+	if !ok {
+		return 0
+	}
+	return m.spec.clock.Now().Sub(at)
+}
+
+func (m *Machine[S, T, Payload]) setEntered(state S, at time.Time) {
+	if uint(state) < uint(len(m.entered)) {
+		m.entered[state] = at
+	}
+}
+
+// markEntered records now as the entry time of the entered states: target[:entryN] and the initial substate, if any.
+func (m *Machine[S, T, Payload]) markEntered(target []S, initial *S, now time.Time) {
+	for _, st := range target {
+		m.setEntered(st, now)
+	}
+	if initial != nil {
+		m.setEntered(*initial, now)
+	}
+}
+
+// enteredEntries returns the entry times of the active hierarchy, outermost first, or nil without tracking.
+func (m *Machine[S, T, Payload]) enteredEntries() []StateEntry[S] {
+	if m.entered == nil {
+		return nil
+	}
+	hierarchy := m.ActiveHierarchy()
+	entries := make([]StateEntry[S], 0, len(hierarchy))
+	for i := len(hierarchy) - 1; i >= 0; i-- {
+		at, _ := m.EnteredAt(hierarchy[i])
+		entries = append(entries, StateEntry[S]{State: hierarchy[i], At: at})
+	}
+	return entries
+}
+
+// DwellStats summarizes the time machines spent in a state before exiting it.
+type DwellStats struct {
+	Count   uint64
+	Sum     time.Duration
+	Min     time.Duration
+	Max     time.Duration
+	Buckets []time.Duration // upper bounds of the histogram buckets, ascending
+	Counts  []uint64        // Counts[i] dwell times fell in (Buckets[i-1], Buckets[i]]; the last entry counts the rest
+}
+
+// Mean returns the average dwell time, or 0 if there were none.
+func (s DwellStats) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / time.Duration(s.Count)
+}
+
+// DefaultDwellBuckets are histogram bucket bounds from one second to one week.
+var DefaultDwellBuckets = []time.Duration{
+	time.Second, 10 * time.Second, time.Minute, 10 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour,
+	7 * 24 * time.Hour,
+}
+
+// DwellAggregator collects histograms of the time machines spend in each state. Attach its Observer to a Builder or to
+// machines that track time in state; every exited state's dwell time is recorded. It is safe for concurrent use.
+type DwellAggregator[S, T ~uint, Payload any] struct {
+	mu      sync.Mutex
+	buckets []time.Duration
+	stats   map[S]*DwellStats
+}
+
+// NewDwellAggregator creates a DwellAggregator with the given ascending bucket bounds, or DefaultDwellBuckets if none
+// are given.
+func NewDwellAggregator[S, T ~uint, Payload any](buckets ...time.Duration) *DwellAggregator[S, T, Payload] {
+	if len(buckets) == 0 {
+		buckets = DefaultDwellBuckets
+	}
+	return &DwellAggregator[S, T, Payload]{buckets: slices.Clone(buckets), stats: make(map[S]*DwellStats)}
+}
+
+// Observer returns the observer that feeds the aggregator.
+func (a *DwellAggregator[S, T, Payload]) Observer() Observer[S, T, Payload] {
+	return Observer[S, T, Payload]{OnTransition: a.record}
+}
+
+func (a *DwellAggregator[S, T, Payload]) record(_ context.Context, event TransitionEvent[S, T, Payload]) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, d := range event.Dwell {
+		a.add(event.Exited[i], d)
+	}
+}
+
+func (a *DwellAggregator[S, T, Payload]) add(state S, d time.Duration) {
+	s := a.stats[state]
+	if s == nil {
+		s = &DwellStats{Min: d, Buckets: a.buckets, Counts: make([]uint64, len(a.buckets)+1)}
+		a.stats[state] = s
+	}
+	s.Count++
+	s.Sum += d
+	s.Min, s.Max = min(s.Min, d), max(s.Max, d)
+	i, _ := slices.BinarySearch(a.buckets, d)
+	s.Counts[i]++
+}
+
+// Stats returns the statistics of the state; the zero DwellStats if it was never exited.
+func (a *DwellAggregator[S, T, Payload]) Stats(state S) DwellStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s := a.stats[state]
+	if s == nil {
+		return DwellStats{}
+	}
+	stats := *s
+	stats.Counts = slices.Clone(s.Counts)
+	return stats
+}
+
+// States returns the states with statistics, in ascending order.
+func (a *DwellAggregator[S, T, Payload]) States() []S {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	states := make([]S, 0, len(a.stats))
+	for st := range a.stats {
+		states = append(states, st)
+	}
+	slices.Sort(states)
+	return states
+}
@@ -0,0 +1,127 @@
+package fsm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newHierarchySpec builds root > child > grandchild and root > unlocked > locked, where unlock from child enters
+// unlocked and its initial substate locked, and lock from locked re-enters locked.
+func newHierarchySpec(clock Clock, observers ...Observer[state, trigger, payload]) *Spec[state, trigger, payload] {
+	builder := NewBuilder[state, trigger, payload]().WithClock(clock)
+	for _, o := range observers {
+		builder.Observe(o)
+	}
+	builder.From(grandchild).WithParent(child)
+	builder.From(child).WithParent(root)
+	builder.From(unlocked).WithParent(root).WithInitial(locked)
+	builder.From(locked).WithParent(unlocked)
+	builder.From(child).On(unlock).To(unlocked)
+	builder.From(locked).On(lock).To(locked)
+	return builder.Build()
+}
+
+func TestMachine_TrackTimeInState(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("records entry times per active hierarchy level", func(t *testing.T) {
+		require := require.New(t)
+
+		/* ---------------------------------- Given --------------------------------- */
+		clock := &stepClock{now: t0}
+		machine := New(newHierarchySpec(clock), grandchild).TrackTimeInState()
+
+		/* ---------------------------------- When ---------------------------------- */
+		clock.now = t0.Add(time.Hour)
+		require.NoError(machine.Fire(t.Context(), unlock, payload{}))
+		clock.now = t0.Add(3 * time.Hour)
+
+		/* ---------------------------------- Then ---------------------------------- */
+		at, ok := machine.EnteredAt(root)
+		require.True(ok)
+		require.Equal(t0, at, "the common ancestor is neither exited nor re-entered")
+		at, ok = machine.EnteredAt(locked)
+		require.True(ok)
+		require.Equal(t0.Add(time.Hour), at)
+		_, ok = machine.EnteredAt(grandchild)
+		require.False(ok, "exited states have no entry time")
+		require.Equal(3*time.Hour, machine.TimeInState(root))
+		require.Equal(2*time.Hour, machine.TimeInState(unlocked))
+		require.Zero(machine.TimeInState(child))
+	})
+
+	t.Run("is off by default", func(t *testing.T) {
+		machine := New(newHierarchySpec(&stepClock{now: t0}), grandchild)
+
+		_, ok := machine.EnteredAt(grandchild)
+
+		require.False(t, ok)
+		require.Zero(t, machine.TimeInState(grandchild))
+		require.Empty(t, machine.Snapshot().Entered)
+	})
+
+	t.Run("survives snapshot and restore", func(t *testing.T) {
+		require := require.New(t)
+
+		clock := &stepClock{now: t0}
+		spec := newHierarchySpec(clock)
+		machine := New(spec, grandchild).TrackTimeInState()
+		clock.now = t0.Add(time.Minute)
+		require.NoError(machine.Fire(t.Context(), unlock, payload{}))
+
+		snapshot := machine.Snapshot()
+		restored := Restore(spec, snapshot)
+
+		require.Equal([]StateEntry[state]{
+			{State: root, At: t0},
+			{State: unlocked, At: t0.Add(time.Minute)},
+			{State: locked, At: t0.Add(time.Minute)},
+		}, snapshot.Entered)
+		at, ok := restored.EnteredAt(root)
+		require.True(ok)
+		require.Equal(t0, at)
+	})
+}
+
+func TestDwellAggregator(t *testing.T) {
+	require := require.New(t)
+
+	/* ---------------------------------- Given --------------------------------- */
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := &stepClock{now: t0}
+	aggregator := NewDwellAggregator[state, trigger, payload](time.Minute, time.Hour)
+	var dwell [][]time.Duration
+	spec := newHierarchySpec(clock, aggregator.Observer(), Observer[state, trigger, payload]{
+		OnTransition: func(_ context.Context, e TransitionEvent[state, trigger, payload]) { dwell = append(dwell, e.Dwell) },
+	})
+	untracked := New(spec, grandchild)
+
+	/* ---------------------------------- When ---------------------------------- */
+	for _, d := range []time.Duration{30 * time.Second, 2 * time.Hour} {
+		clock.now = t0
+		machine := New(spec, grandchild).TrackTimeInState()
+		clock.now = t0.Add(d)
+		require.NoError(machine.Fire(t.Context(), unlock, payload{}))
+		clock.now = t0.Add(d + 10*time.Minute)
+		require.NoError(machine.Fire(t.Context(), lock, payload{}))
+	}
+	require.NoError(untracked.Fire(t.Context(), unlock, payload{}))
+
+	/* ---------------------------------- Then ---------------------------------- */
+	require.Equal([]time.Duration{30 * time.Second, 30 * time.Second}, dwell[0], "grandchild and child were exited")
+	require.Nil(dwell[len(dwell)-1], "machines that do not track time in state report no dwell")
+	require.Equal([]state{locked, child, grandchild}, aggregator.States())
+	require.Equal(DwellStats{
+		Count:   2,
+		Sum:     30*time.Second + 2*time.Hour,
+		Min:     30 * time.Second,
+		Max:     2 * time.Hour,
+		Buckets: []time.Duration{time.Minute, time.Hour},
+		Counts:  []uint64{1, 0, 1},
+	}, aggregator.Stats(grandchild))
+	require.Equal(10*time.Minute, aggregator.Stats(locked).Mean())
+	require.Zero(aggregator.Stats(root).Count)
+}
@@ -0,0 +1,172 @@
+package fsm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ScheduledTimer is a durable state timeout: Trigger is due to fire on machine MachineID at At, unless the machine
+// exits State first. A timer is identified by (MachineID, State, Trigger); At is its payload.
+type ScheduledTimer[ID comparable, S, T ~uint] struct {
+	MachineID ID        `json:"machineId"`
+	State     S         `json:"state"`
+	Trigger   T         `json:"trigger"`
+	At        time.Time `json:"at"`
+}
+
+// TimerStore persists state timeouts, declared with After, for workflows that outlive the process. Implementations
+// must be safe for concurrent use.
+type TimerStore[ID comparable, S, T ~uint] interface {
+	// Schedule stores the timer, replacing any timer with the same identity.
+	Schedule(ctx context.Context, timer ScheduledTimer[ID, S, T]) error
+	// Cancel removes the timer with the same identity as timer, if any. At is ignored.
+	Cancel(ctx context.Context, timer ScheduledTimer[ID, S, T]) error
+	// Due returns every timer with At not after now, ordered by At.
+	Due(ctx context.Context, now time.Time) ([]ScheduledTimer[ID, S, T], error)
+}
+
+// timerKey is the identity of a ScheduledTimer.
+type timerKey[ID comparable, S, T ~uint] struct {
+	machineID ID
+	state     S
+	trigger   T
+}
+
+func keyOf[ID comparable, S, T ~uint](t ScheduledTimer[ID, S, T]) timerKey[ID, S, T] {
+	return timerKey[ID, S, T]{machineID: t.MachineID, state: t.State, trigger: t.Trigger}
+}
+
+// dueTimers returns the timers due at now, ordered by At.
+func dueTimers[ID comparable, S, T ~uint](timers map[timerKey[ID, S, T]]ScheduledTimer[ID, S, T], now time.Time) []ScheduledTimer[ID, S, T] {
+	return slices.DeleteFunc(sortedTimers(timers), func(t ScheduledTimer[ID, S, T]) bool { return t.At.After(now) })
+}
+
+// sortedTimers returns every timer, ordered by At.
+func sortedTimers[ID comparable, S, T ~uint](timers map[timerKey[ID, S, T]]ScheduledTimer[ID, S, T]) []ScheduledTimer[ID, S, T] {
+	list := make([]ScheduledTimer[ID, S, T], 0, len(timers))
+	for _, t := range timers {
+		list = append(list, t)
+	}
+	slices.SortStableFunc(list, func(a, b ScheduledTimer[ID, S, T]) int { return a.At.Compare(b.At) })
+	return list
+}
+
+// MemoryTimerStore is an in-memory TimerStore, for tests and single-process use.
+type MemoryTimerStore[ID comparable, S, T ~uint] struct {
+	mu     sync.Mutex
+	timers map[timerKey[ID, S, T]]ScheduledTimer[ID, S, T]
+}
+
+// NewMemoryTimerStore creates an empty MemoryTimerStore.
+func NewMemoryTimerStore[ID comparable, S, T ~uint]() *MemoryTimerStore[ID, S, T] {
+	return &MemoryTimerStore[ID, S, T]{timers: make(map[timerKey[ID, S, T]]ScheduledTimer[ID, S, T])}
+}
+
+// Schedule stores the timer.
+func (s *MemoryTimerStore[ID, S, T]) Schedule(_ context.Context, timer ScheduledTimer[ID, S, T]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timers[keyOf(timer)] = timer
+	return nil
+}
+
+// Cancel removes the timer.
+func (s *MemoryTimerStore[ID, S, T]) Cancel(_ context.Context, timer ScheduledTimer[ID, S, T]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.timers, keyOf(timer))
+	return nil
+}
+
+// Due returns the timers due at now.
+func (s *MemoryTimerStore[ID, S, T]) Due(_ context.Context, now time.Time) ([]ScheduledTimer[ID, S, T], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return dueTimers(s.timers, now), nil
+}
+
+// FileTimerStore is a TimerStore backed by a JSON file, for local use. Every change rewrites the whole file
+// atomically, so it suits modest numbers of timers. ID must be JSON-serializable. It is safe for concurrent use
+// within one process; separate processes must not share the file.
+type FileTimerStore[ID comparable, S, T ~uint] struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileTimerStore creates a FileTimerStore persisting to path. The file is created on the first Schedule.
+func NewFileTimerStore[ID comparable, S, T ~uint](path string) *FileTimerStore[ID, S, T] {
+	return &FileTimerStore[ID, S, T]{path: path}
+}
+
+// Schedule stores the timer.
+func (s *FileTimerStore[ID, S, T]) Schedule(_ context.Context, timer ScheduledTimer[ID, S, T]) error {
+	return s.update(func(timers map[timerKey[ID, S, T]]ScheduledTimer[ID, S, T]) {
+		timers[keyOf(timer)] = timer
+	})
+}
+
+// Cancel removes the timer.
+func (s *FileTimerStore[ID, S, T]) Cancel(_ context.Context, timer ScheduledTimer[ID, S, T]) error {
+	return s.update(func(timers map[timerKey[ID, S, T]]ScheduledTimer[ID, S, T]) {
+		delete(timers, keyOf(timer))
+	})
+}
+
+// Due returns the timers due at now.
+func (s *FileTimerStore[ID, S, T]) Due(_ context.Context, now time.Time) ([]ScheduledTimer[ID, S, T], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	timers, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return dueTimers(timers, now), nil
+}
+
+func (s *FileTimerStore[ID, S, T]) update(change func(map[timerKey[ID, S, T]]ScheduledTimer[ID, S, T])) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	timers, err := s.read()
+	if err != nil {
+		return err
+	}
+	change(timers)
+	return s.write(timers)
+}
+
+func (s *FileTimerStore[ID, S, T]) read() (map[timerKey[ID, S, T]]ScheduledTimer[ID, S, T], error) {
+	timers := make(map[timerKey[ID, S, T]]ScheduledTimer[ID, S, T])
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return timers, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading timer file: %w", err)
+	}
+	var list []ScheduledTimer[ID, S, T]
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("decoding timer file %s: %w", s.path, err)
+	}
+	for _, t := range list {
+		timers[keyOf(t)] = t
+	}
+	return timers, nil
+}
+
+func (s *FileTimerStore[ID, S, T]) write(timers map[timerKey[ID, S, T]]ScheduledTimer[ID, S, T]) error {
+	data, err := json.MarshalIndent(sortedTimers(timers), "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding timers: %w", err)
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("writing timer file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package fsm_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tobbstr/fsm"
+)
+
+func TestTimerStores(t *testing.T) {
+	type timer = fsm.ScheduledTimer[string, orderState, orderTrigger]
+
+	stores := map[string]func(t *testing.T) fsm.TimerStore[string, orderState, orderTrigger]{
+		"memory": func(t *testing.T) fsm.TimerStore[string, orderState, orderTrigger] {
+			return fsm.NewMemoryTimerStore[string, orderState, orderTrigger]()
+		},
+		"file": func(t *testing.T) fsm.TimerStore[string, orderState, orderTrigger] {
+			return fsm.NewFileTimerStore[string, orderState, orderTrigger](filepath.Join(t.TempDir(), "timers.json"))
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			/* ---------------------------------- Given --------------------------------- */
+			store := newStore(t)
+			now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			expire := timer{MachineID: "order-1", State: awaitingOrderPayment, Trigger: expireOrder, At: now.Add(time.Hour)}
+			closeSoon := timer{MachineID: "order-1", State: orderOpen, Trigger: closeOrder, At: now.Add(time.Minute)}
+			other := timer{MachineID: "order-2", State: awaitingOrderPayment, Trigger: expireOrder, At: now.Add(time.Minute)}
+
+			/* ---------------------------------- When ---------------------------------- */
+			require.NoError(store.Schedule(t.Context(), expire))
+			require.NoError(store.Schedule(t.Context(), closeSoon))
+			require.NoError(store.Schedule(t.Context(), other))
+			rescheduled := expire
+			rescheduled.At = now.Add(30 * time.Second)
+			require.NoError(store.Schedule(t.Context(), rescheduled)) // same identity replaces
+			require.NoError(store.Cancel(t.Context(), timer{MachineID: "order-2", State: awaitingOrderPayment, Trigger: expireOrder}))
+
+			/* ---------------------------------- Then ---------------------------------- */
+			due, err := store.Due(t.Context(), now)
+			require.NoError(err)
+			require.Empty(due)
+
+			due, err = store.Due(t.Context(), now.Add(time.Minute))
+			require.NoError(err)
+			require.Equal([]timer{rescheduled, closeSoon}, due)
+		})
+	}
+}
+
+func TestFileTimerStore_SurvivesReopen(t *testing.T) {
+	require := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "timers.json")
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	timer := fsm.ScheduledTimer[string, orderState, orderTrigger]{
+		MachineID: "order-1", State: awaitingOrderPayment, Trigger: expireOrder, At: at,
+	}
+	require.NoError(fsm.NewFileTimerStore[string, orderState, orderTrigger](path).Schedule(t.Context(), timer))
+
+	due, err := fsm.NewFileTimerStore[string, orderState, orderTrigger](path).Due(t.Context(), at)
+
+	require.NoError(err)
+	require.Equal([]fsm.ScheduledTimer[string, orderState, orderTrigger]{timer}, due)
+}